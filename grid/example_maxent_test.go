@@ -41,7 +41,11 @@ func ExampleMaxEntIRL() {
 	height := 20
 	alpha := 0.01
 
-	g := grid.NewGridModel(width, height)
+	g, err := grid.NewGridModel(width, height)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 	feature := g.CreateRandomFeature(nFeature)
 	expert := maxent.NewLinearModel(g.Model, feature, false)
 	for i := range expert.Theta {
@@ -60,11 +64,17 @@ func ExampleMaxEntIRL() {
 	}
 	var gTrain *grid.GridWorld
 	for _, alpha := range []float64{0.01, 0.02, 0.04} {
-		gTrain = grid.NewGridModel(width, height)
+		if gTrain, err = grid.NewGridModel(width, height); err != nil {
+			fmt.Println(err)
+			return
+		}
 		fmt.Println(alpha)
 		trainer := maxent.NewLinearModel(gTrain.Model, feature, false)
 		// maxent.UpdateCost(g.Model, feature, trainer.Theta, nil)
-		trainer.Fit(demos, nEpoch, batchSize, gamma)
+		if err := trainer.Fit(demos, nEpoch, batchSize, gamma); err != nil {
+			fmt.Println(err)
+			return
+		}
 
 		score := base.CosineSimilarity(expert.Theta, trainer.Theta)
 		fmt.Printf("<w>\n")
@@ -78,9 +88,17 @@ func ExampleMaxEntIRL() {
 		// 	fmt.Printf("learned: %v\n", trainer.UniqueCost[uniqueIndex - 1: uniqueIndex + 2])
 		// 	fmt.Printf("target: %v\n", expert.UniqueCost[uniqueIndex - 1: uniqueIndex + 2])
 		// }
-		fmt.Printf("<dist>\n [%.2f", math.Log10(1 - trainer.EvalActionDist(demos[0])))
-		for _, demo := range demos[1:] {
-			fmt.Printf(", %.2f", math.Log10(1 - trainer.EvalActionDist(demo)))
+		fmt.Printf("<dist>\n [")
+		for i, demo := range demos {
+			sim, err := trainer.EvalActionDist(demo)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if i > 0 {
+				fmt.Printf(", ")
+			}
+			fmt.Printf("%.2f", math.Log10(1 - sim))
 		}
 		fmt.Printf("]\n")
 	}
@@ -9,7 +9,11 @@ import (
 func ExampleValueIterator() {
 	width := 3
 	height := 3
-	g := grid.NewGridModel(width, height)
+	g, err := grid.NewGridModel(width, height)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 	goalID, _ := g.StateIDOf(width - 1, height - 1)
 	vi := mdp.NewValueIterator(g.Model)
 	vi.SetAbsorbingState(goalID)
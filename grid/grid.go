@@ -37,7 +37,7 @@ func (g *GridWorld) CoordinateOf(stateID int) (x, y int, ok bool) {
 	return
 }
 
-func NewGridModel(width, height int) *GridWorld {
+func NewGridModel(width, height int) (*GridWorld, error) {
 	moves := []gridMove{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
 	nMove := len(moves)
 	// coordinateOf := make(map[int]GridCoordinate)
@@ -60,8 +60,12 @@ func NewGridModel(width, height int) *GridWorld {
 			}
 		}
 	}
-	g.Model = mdp.NewModel(stateIDs, actions)
-	return &g
+	m, err := mdp.NewModel(stateIDs, actions)
+	if err != nil {
+		return nil, err
+	}
+	g.Model = m
+	return &g, nil
 }
 
 func (g *GridWorld) CreateRandomFeature(nFeature int) *maxent.Feature {
@@ -1,7 +1,6 @@
 package maxent
 
 import (
-	"fmt"
 	"github.com/misteroda/go-rl/mdp"
 )
 
@@ -57,6 +56,9 @@ func (m *ModelDemonstrationLoader) LoadInitialState(goalID int) ([]InitialState,
 	return s, nil
 }
 
+// LoadTransitionVisitation generates trajectories from the initial states of a goal
+// and counts their transitions.
+// It returns *mdp.UnreachableGoalError or *mdp.DeadEndError if a trajectory does not reach the goal.
 func (m *ModelDemonstrationLoader) LoadTransitionVisitation(goalID int) ([]TransitionVisitation, error) {
 	initialStates, ok := m.initialStateOf[goalID]
 	if !ok {
@@ -66,10 +68,9 @@ func (m *ModelDemonstrationLoader) LoadTransitionVisitation(goalID int) ([]Trans
 	transitionCount := make(map[int]map[int]int)
 	for _, s := range initialStates {
 		for j := 0; j < s.Count; j++ {
-			traj, ok := m.vi.GenerateTrajectory(s.ID, goalID, m.maxStep)
-			if !ok {
-				fmt.Printf("Unable to reach the goal: %d\n", goalID)
-				continue
+			traj, err := m.vi.GenerateTrajectory(s.ID, goalID, m.maxStep)
+			if err != nil {
+				return nil, err
 			}
 			fromID := traj[0]
 			for _, toID := range traj[1:] {
@@ -119,15 +120,19 @@ func NewDemonstration(m *mdp.Model, goalID int, loader DemonstrationLoader) (*De
 	var nSample int
 	for _, s := range initialStates {
 		state, ok := m.StateOf[s.ID]
-		if !ok { continue }
+		if !ok {
+			return nil, &mdp.UnknownStateError{ID: s.ID}
+		}
 		initialStateDist[state.Index()] += float64(s.Count)
 		nSample += s.Count
 	}
 	
 	actionDist := make([]float64, m.NumActions())
 	for _, t := range transitions {
-		action, ok := m.ActionByID(t.FromID, t.ToID)
-		if !ok { continue }
+		action, err := m.ActionByID(t.FromID, t.ToID)
+		if err != nil {
+			return nil, err
+		}
 		actionDist[action.Index()] += float64(t.Count)
 	}
 	for i := range initialStateDist {
@@ -1,6 +1,7 @@
 package maxent
 
 import (
+	"context"
 	"math"
 	"math/rand"
 	"sync"
@@ -43,6 +44,15 @@ type LinearModel struct {
 	Feature *Feature
 	Theta base.Vector
 	UniqueCost base.Vector
+	progress func(EpochProgress)
+	newSolver mdp.SolverFactory
+}
+
+// EpochProgress represents the state of training at the end of an epoch.
+type EpochProgress struct {
+	Epoch int
+	LearningRate float64
+	GradNorm float64 // norm of the feature expectation difference
 }
 
 func NewLinearModel(m *mdp.Model, f *Feature, uniqueCostFlag bool) *LinearModel {
@@ -61,17 +71,37 @@ func NewLinearModel(m *mdp.Model, f *Feature, uniqueCostFlag bool) *LinearModel
 		Feature: f,
 		Theta: theta,
 		UniqueCost : uniqueCost,
+		newSolver: mdp.NewValueIteratorSolver,
 	}
 }
 
-func (l *LinearModel) EvalActionDist(demo *Demonstration) float64 {
-	vi := mdp.NewValueIterator(l.mdp)
-	vi.InitAbsorbingState()
-	vi.SetAbsorbingState(demo.goalID)
-	vi.RunValueIteration()
-	vi.UpdatePolicy()
-	_, actionDist := vi.StateActionVisitation(demo.initialStateDist)
-	return base.CosineSimilarity(actionDist, demo.actionDist)
+// SetProgressFunc sets a hook which is called at the end of each epoch of Fit.
+func (l *LinearModel) SetProgressFunc(progress func(EpochProgress)) {
+	l.progress = progress
+}
+
+// SetSolverFactory sets a constructor of the planner used in EvalActionDist and Fit.
+// Fit constructs one Solver per CPU. The default is mdp.NewValueIteratorSolver.
+func (l *LinearModel) SetSolverFactory(newSolver mdp.SolverFactory) {
+	l.newSolver = newSolver
+}
+
+func (l *LinearModel) EvalActionDist(demo *Demonstration) (float64, error) {
+	ctx := context.Background()
+	solver := l.newSolver(l.mdp)
+	solver.InitAbsorbingState()
+	if err := solver.SetAbsorbingState(demo.goalID); err != nil {
+		return 0, err
+	}
+	if err := solver.Solve(ctx); err != nil {
+		return 0, err
+	}
+	solver.UpdatePolicy()
+	_, actionDist, err := solver.StateActionVisitationContext(ctx, demo.initialStateDist)
+	if err != nil {
+		return 0, err
+	}
+	return base.CosineSimilarity(actionDist, demo.actionDist), nil
 }
 
 func (l *LinearModel) ComputeFeatureExpectation(actionDist []float64) []float64 {
@@ -84,7 +114,16 @@ func (l *LinearModel) ComputeFeatureExpectation(actionDist []float64) []float64
 	return featureExpectation
 }
 
-func (l *LinearModel) Fit(demonstrations []*Demonstration, nEpoch, numCPU int, gamma float64) {
+func (l *LinearModel) Fit(demonstrations []*Demonstration, nEpoch, numCPU int, gamma float64) error {
+	return l.FitContext(context.Background(), demonstrations, nEpoch, numCPU, gamma)
+}
+
+// FitContext runs Fit until nEpoch epochs finish or ctx is done.
+// If ctx is done, it returns ctx.Err() keeping Theta of the last finished epoch.
+// ctx is checked at each epoch, and an error of a solver stops the others of the epoch.
+func (l *LinearModel) FitContext(ctx context.Context, demonstrations []*Demonstration, nEpoch, numCPU int, gamma float64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	// theta := make([]float64, feature.n)
 	gradSum := base.Vector(make([]float64, l.Feature.M))
 
@@ -96,33 +135,47 @@ func (l *LinearModel) Fit(demonstrations []*Demonstration, nEpoch, numCPU int, g
 	}
 	
 	gradMutex := &sync.Mutex{}
-	viGroup := make([]*mdp.ValueIterator, numCPU)
+	solverGroup := make([]mdp.Solver, numCPU)
 	wg := sync.WaitGroup{}
 	for i := 0; i < numCPU; i++ {
-		viGroup[i] = mdp.NewValueIterator(l.mdp)
+		solverGroup[i] = l.newSolver(l.mdp)
 	}
 	gamma /= float64(numCPU)
 	// vi := NewValueIterator(l.mdp)
 	for i := 0; i < nEpoch; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		gradSum.Fill(0.0)
 		if uniqueGradSum != nil {
 			uniqueGradSum.Fill(0.0)
 		}		
-		for _, vi := range viGroup {
+		var firstErr error
+		for _, solver := range solverGroup {
 			wg.Add(1)
 			demo := demonstrations[rand.Intn(len(demonstrations))]
-			go func(vi *mdp.ValueIterator, demo *Demonstration) {
+			go func(solver mdp.Solver, demo *Demonstration) {
 				defer wg.Done()
-				grad, uniqueCost := l.ComputeFeatureExpectationDifference(vi, demo)
+				grad, uniqueCost, err := l.computeFeatureExpectationDifference(ctx, solver, demo)
 				gradMutex.Lock()
+				defer gradMutex.Unlock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					return
+				}
 				gradSum.Add(base.Vector(grad))
 				if uniqueGradSum != nil {
 					uniqueGradSum.Add(base.Vector(uniqueCost))
 				}
-				gradMutex.Unlock()	
-			}(vi, demo)
+			}(solver, demo)
 		}
 		wg.Wait()
+		if firstErr != nil {
+			return firstErr
+		}
 		// Update theta with exponentiated gradient ascent
 		if l.UniqueCost != nil {
 			l.ExponentiatedGradientAscent(gradSum, gamma)
@@ -131,10 +184,15 @@ func (l *LinearModel) Fit(demonstrations []*Demonstration, nEpoch, numCPU int, g
 		}
 		// Update mdp.cost with new theta
 		cost := l.ComputeCost()
-		l.mdp.UpdateReward(cost)
+		if err := l.mdp.UpdateReward(cost); err != nil {
+			return err
+		}
+		if l.progress != nil {
+			l.progress(EpochProgress{Epoch: i, LearningRate: gamma, GradNorm: gradSum.Norm()})
+		}
 		gamma *= gradDecay
-		// fmt.Printf("gamma : %.3f, grad : %.3f, theta : %v\n", gamma, gradSum, l.Theta)
 	}
+	return nil
 }
 
 
@@ -174,12 +232,23 @@ func (l *LinearModel) ComputeCost() []float64 {
 	return cost
 }
 
-func (l *LinearModel) ComputeFeatureExpectationDifference(vi *mdp.ValueIterator, demo *Demonstration) ([]float64, []float64) {
-	vi.InitAbsorbingState()
-	vi.SetAbsorbingState(demo.goalID)
-	vi.RunValueIteration()
-	vi.UpdatePolicy()
-	_, actionDist := vi.StateActionVisitation(demo.initialStateDist)
+func (l *LinearModel) ComputeFeatureExpectationDifference(solver mdp.Solver, demo *Demonstration) ([]float64, []float64, error) {
+	return l.computeFeatureExpectationDifference(context.Background(), solver, demo)
+}
+
+func (l *LinearModel) computeFeatureExpectationDifference(ctx context.Context, solver mdp.Solver, demo *Demonstration) ([]float64, []float64, error) {
+	solver.InitAbsorbingState()
+	if err := solver.SetAbsorbingState(demo.goalID); err != nil {
+		return nil, nil, err
+	}
+	if err := solver.Solve(ctx); err != nil {
+		return nil, nil, err
+	}
+	solver.UpdatePolicy()
+	_, actionDist, err := solver.StateActionVisitationContext(ctx, demo.initialStateDist)
+	if err != nil {
+		return nil, nil, err
+	}
 	featureExpectation := l.ComputeFeatureExpectation(actionDist)
 	expertFeatureExpectation := l.ComputeFeatureExpectation(demo.actionDist)
 	// base.Vector(expertFeatureExpectation).Sub(base.Vector(featureExpectation))
@@ -187,10 +256,10 @@ func (l *LinearModel) ComputeFeatureExpectationDifference(vi *mdp.ValueIterator,
 		expertFeatureExpectation[i] -= featureExpectation[i]
 	}
 	if l.UniqueCost == nil {
-		return expertFeatureExpectation, nil
+		return expertFeatureExpectation, nil, nil
 	}
 	for i := range actionDist {
 		actionDist[i] = demo.actionDist[i] - actionDist[i]
 	}
-	return expertFeatureExpectation, actionDist 
+	return expertFeatureExpectation, actionDist, nil
 } 
@@ -0,0 +1,308 @@
+package mdp
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	probabilityTolerance = 1e-9
+)
+
+// ValidationReport represents problems found in a Model.
+type ValidationReport struct {
+	DeadEnds []int // IDs of states without actions
+	NotReachingGoal []int // IDs of states which cannot reach any goal
+	Unreachable []int // IDs of states which cannot be reached from the start distribution
+	DuplicateActions [][]int // groups of indices of actions with identical outcomes from the same state
+	InvalidProbabilities []int // indices of actions whose outcome probabilities do not sum to 1
+	Components [][]int // IDs of states in each strongly connected component
+	goalIDs []int
+}
+
+// OK reports whether no problem is found.
+// Dead ends are allowed only for goal states.
+func (r *ValidationReport) OK() bool {
+	for _, id := range r.DeadEnds {
+		isGoal := false
+		for _, goalID := range r.goalIDs {
+			isGoal = isGoal || id == goalID
+		}
+		if !isGoal {
+			return false
+		}
+	}
+	return len(r.NotReachingGoal) == 0 &&
+		len(r.Unreachable) == 0 &&
+		len(r.DuplicateActions) == 0 &&
+		len(r.InvalidProbabilities) == 0
+}
+
+// Validate analyzes the Model for given goal states and start distribution.
+// If goalIDs is empty, NotReachingGoal is not computed.
+// If initialStateDist is nil, Unreachable is not computed.
+func (m *Model) Validate(goalIDs []int, initialStateDist []float64) (*ValidationReport, error) {
+	r := &ValidationReport{
+		DeadEnds: m.DeadEnds(),
+		DuplicateActions: m.DuplicateActions(),
+		InvalidProbabilities: m.InvalidProbabilities(),
+		Components: m.StronglyConnectedComponents(),
+		goalIDs: goalIDs,
+	}
+	var err error
+	if len(goalIDs) > 0 {
+		if r.NotReachingGoal, err = m.StatesNotReaching(goalIDs); err != nil {
+			return nil, err
+		}
+	}
+	if initialStateDist != nil {
+		if r.Unreachable, err = m.UnreachableStates(initialStateDist); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// DeadEnds returns the IDs of states without actions.
+func (m *Model) DeadEnds() []int {
+	ids := make([]int, 0)
+	for i := range m.states {
+		if len(m.states[i].actions) == 0 {
+			ids = append(ids, m.states[i].id)
+		}
+	}
+	return ids
+}
+
+// StatesNotReaching returns the IDs of states which cannot reach any of given goal states.
+func (m *Model) StatesNotReaching(goalIDs []int) ([]int, error) {
+	visited := make([]bool, len(m.states))
+	queue := make([]*State, 0, len(goalIDs))
+	for _, id := range goalIDs {
+		s, ok := m.StateOf[id]
+		if !ok {
+			return nil, &UnknownStateError{id}
+		}
+		if !visited[s.index] {
+			visited[s.index] = true
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, tr := range s.transitions {
+			prev := tr.action.state
+			if tr.p > 0 && !visited[prev.index] {
+				visited[prev.index] = true
+				queue = append(queue, prev)
+			}
+		}
+	}
+	return m.unvisitedIDs(visited), nil
+}
+
+// UnreachableStates returns the IDs of states which cannot be reached
+// from states with positive probability in initialStateDist.
+func (m *Model) UnreachableStates(initialStateDist []float64) ([]int, error) {
+	if len(initialStateDist) != len(m.states) {
+		return nil, &DimensionMismatchError{Got: len(initialStateDist), Want: len(m.states)}
+	}
+	visited := make([]bool, len(m.states))
+	queue := make([]*State, 0)
+	for i, d := range initialStateDist {
+		if d > 0 {
+			visited[i] = true
+			queue = append(queue, &m.states[i])
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, a := range s.actions {
+			for _, tr := range a.transitions {
+				next := tr.state
+				if tr.p > 0 && !visited[next.index] {
+					visited[next.index] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return m.unvisitedIDs(visited), nil
+}
+
+func (m *Model) unvisitedIDs(visited []bool) []int {
+	ids := make([]int, 0)
+	for i, ok := range visited {
+		if !ok {
+			ids = append(ids, m.states[i].id)
+		}
+	}
+	return ids
+}
+
+// DuplicateActions returns groups of indices of actions
+// which have identical outcomes and probabilities from the same state.
+func (m *Model) DuplicateActions() [][]int {
+	groups := make([][]int, 0)
+	for i := range m.states {
+		groupOf := make(map[string][]int)
+		keys := make([]string, 0)
+		for _, a := range m.states[i].actions {
+			key := outcomeKey(a)
+			if _, ok := groupOf[key]; !ok {
+				keys = append(keys, key)
+			}
+			groupOf[key] = append(groupOf[key], a.index)
+		}
+		for _, key := range keys {
+			if len(groupOf[key]) > 1 {
+				groups = append(groups, groupOf[key])
+			}
+		}
+	}
+	return groups
+}
+
+// outcomeKey encodes the outcomes of an action regardless of their order.
+func outcomeKey(a *Action) string {
+	outcomes := make([]string, len(a.transitions))
+	for i, tr := range a.transitions {
+		outcomes[i] = strconv.Itoa(tr.state.index) + ":" + strconv.FormatFloat(tr.p, 'g', -1, 64)
+	}
+	sort.Strings(outcomes)
+	return strings.Join(outcomes, ",")
+}
+
+// InvalidProbabilities returns the indices of actions
+// whose outcome probabilities are negative or do not sum to 1.
+func (m *Model) InvalidProbabilities() []int {
+	indices := make([]int, 0)
+	for i := range m.actions {
+		a := &m.actions[i]
+		if a.state == nil { continue }
+		z := 0.0
+		valid := true
+		for _, tr := range a.transitions {
+			z += tr.p
+			valid = valid && tr.p >= 0
+		}
+		if !valid || math.Abs(z - 1) > probabilityTolerance {
+			indices = append(indices, a.index)
+		}
+	}
+	return indices
+}
+
+// StronglyConnectedComponents returns the IDs of states in each strongly connected component
+// of the graph whose edges are transitions with positive probability.
+// Components are in reverse topological order, i.e. no transition leads to a preceding component.
+func (m *Model) StronglyConnectedComponents() [][]int {
+	return m.stronglyConnectedComponents(func(s *State) []*Action { return s.actions })
+}
+
+// stronglyConnectedComponents returns the components of the graph restricted to
+// the actions returned by actionsOf.
+func (m *Model) stronglyConnectedComponents(actionsOf func(s *State) []*Action) [][]int {
+	// iterative Tarjan's algorithm
+	n := len(m.states)
+	order := make([]int, n) // discovery order starting with 1, 0 if not visited
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	stack := make([]int, 0)
+	components := make([][]int, 0)
+	type frame struct {
+		state, action, transition int
+	}
+	counter := 0
+	for root := range m.states {
+		if order[root] > 0 { continue }
+		callStack := []frame{{root, 0, 0}}
+		counter++
+		order[root], low[root] = counter, counter
+		stack = append(stack, root)
+		onStack[root] = true
+		for len(callStack) > 0 {
+			f := &callStack[len(callStack)-1]
+			actions := actionsOf(&m.states[f.state])
+			if f.action < len(actions) {
+				a := actions[f.action]
+				if f.transition >= len(a.transitions) {
+					f.action++
+					f.transition = 0
+					continue
+				}
+				tr := a.transitions[f.transition]
+				f.transition++
+				next := tr.state.index
+				if tr.p <= 0 { continue }
+				if order[next] == 0 {
+					counter++
+					order[next], low[next] = counter, counter
+					stack = append(stack, next)
+					onStack[next] = true
+					callStack = append(callStack, frame{next, 0, 0})
+				} else if onStack[next] && order[next] < low[f.state] {
+					low[f.state] = order[next]
+				}
+				continue
+			}
+			idx := f.state
+			if low[idx] == order[idx] {
+				component := make([]int, 0)
+				for {
+					top := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[top] = false
+					component = append(component, m.states[top].id)
+					if top == idx { break }
+				}
+				components = append(components, component)
+			}
+			callStack = callStack[:len(callStack)-1]
+			if len(callStack) > 0 {
+				parent := callStack[len(callStack)-1].state
+				if low[idx] < low[parent] {
+					low[parent] = low[idx]
+				}
+			}
+		}
+	}
+	return components
+}
+
+// closedComponents returns the strongly connected components of the graph restricted to
+// the actions returned by actionsOf which no transition leaves.
+func (m *Model) closedComponents(actionsOf func(s *State) []*Action) [][]int {
+	components := m.stronglyConnectedComponents(actionsOf)
+	componentOf := make([]int, len(m.states))
+	for c, component := range components {
+		for _, id := range component {
+			componentOf[m.StateOf[id].index] = c
+		}
+	}
+	isClosed := make([]bool, len(components))
+	for c := range isClosed {
+		isClosed[c] = true
+	}
+	for i := range m.states {
+		s := &m.states[i]
+		for _, a := range actionsOf(s) {
+			for _, tr := range a.transitions {
+				if tr.p > 0 && componentOf[tr.state.index] != componentOf[i] {
+					isClosed[componentOf[i]] = false
+				}
+			}
+		}
+	}
+	closed := make([][]int, 0)
+	for c, component := range components {
+		if isClosed[c] {
+			closed = append(closed, component)
+		}
+	}
+	return closed
+}
@@ -0,0 +1,186 @@
+package mdp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"github.com/misteroda/go-rl/base"
+)
+
+const defaultAperiodicity = 0.5
+
+// NotWeaklyCommunicatingError reports a Model with more than one class of states
+// which no action leaves, so that no policy connects them
+// and the optimal gain may depend on the start state.
+// A weakly communicating Model may still have multichain policies, see RecurrentClasses.
+// Classes holds the state IDs of each closed class.
+type NotWeaklyCommunicatingError struct {
+	Classes [][]int
+}
+
+func (e *NotWeaklyCommunicatingError) Error() string {
+	return fmt.Sprintf("mdp: model is not weakly communicating with %d closed classes", len(e.Classes))
+}
+
+// RelativeValueIterator represents Relative Value Iteration algorithm
+// for the average-reward criterion of a continuing task without absorbing states.
+// At convergence, Gain + Bias[s] = max_a Q[a] for each state s and Bias of the reference state is 0.
+type RelativeValueIterator struct {
+	model *Model
+	Gain float64 // average reward per step
+	Bias []float64 // relative state values
+	Q []float64 // state-action values relative to Bias
+	Policy []float64
+	ref int // index of the reference state
+	tau float64 // weight of the aperiodicity transformation
+	convergence
+	progress ProgressFunc
+}
+
+// NewRelativeValueIterator constructs a RelativeValueIterator instance from a given Model.
+func NewRelativeValueIterator(model *Model) *RelativeValueIterator {
+	return NewRelativeValueIteratorWithOptions(model, DefaultOptions())
+}
+
+// NewRelativeValueIteratorWithOptions constructs a RelativeValueIterator instance
+// from a given Model and convergence settings.
+// MinTDError is the tolerance of the span of a Bellman update.
+func NewRelativeValueIteratorWithOptions(model *Model, opts Options) *RelativeValueIterator {
+	rvi := RelativeValueIterator{
+		model: model,
+		Bias: make([]float64, len(model.states)),
+		Q: make([]float64, len(model.actions)),
+		Policy: make([]float64, len(model.actions)),
+		tau: defaultAperiodicity,
+		convergence: convergence{opts},
+	}
+	return &rvi
+}
+
+// SetReferenceState sets the state whose bias is fixed to 0. The default is the first state.
+func (rvi *RelativeValueIterator) SetReferenceState(stateID int) error {
+	state, ok := rvi.model.StateOf[stateID]
+	if !ok { return &UnknownStateError{stateID} }
+	rvi.ref = state.index
+	return nil
+}
+
+// SetAperiodicity sets the weight tau in (0, 1] of the aperiodicity transformation,
+// which mixes each transition with a self-loop of probability 1 - tau
+// so that periodic models, e.g. patrol cycles, converge. The default is 0.5.
+func (rvi *RelativeValueIterator) SetAperiodicity(tau float64) {
+	rvi.tau = tau
+}
+
+// SetProgressFunc sets a hook which is called every ctxCheckInterval sweeps.
+func (rvi *RelativeValueIterator) SetProgressFunc(progress ProgressFunc) {
+	rvi.progress = progress
+}
+
+// Init initializes RelativeValueIterator instance.
+func (rvi *RelativeValueIterator) Init() {
+	rvi.Gain = 0
+	base.Vector(rvi.Bias).Fill(0.0)
+	base.Vector(rvi.Q).Fill(0.0)
+	base.Vector(rvi.Policy).Fill(0.0)
+	rvi.ref = 0
+	rvi.tau = defaultAperiodicity
+}
+
+// RunRelativeValueIteration runs Relative Value Iteration algorithm and updates Gain, Bias and Q.
+// It returns *DeadEndError if a state has no action,
+// and *NotWeaklyCommunicatingError if the Model has more than one class closed under every action.
+func (rvi *RelativeValueIterator) RunRelativeValueIteration() (ConvergenceReport, error) {
+	return rvi.RunRelativeValueIterationContext(context.Background())
+}
+
+// RunRelativeValueIterationContext runs RunRelativeValueIteration until convergence or cancellation of ctx.
+// If ctx is done, it returns ctx.Err() leaving Gain, Bias and Q partially updated.
+func (rvi *RelativeValueIterator) RunRelativeValueIterationContext(ctx context.Context) (ConvergenceReport, error) {
+	m := rvi.model
+	report := ConvergenceReport{Iterations: make([]int, 1)}
+	if deadEnds := m.DeadEnds(); len(deadEnds) > 0 {
+		return report, &DeadEndError{deadEnds[0]}
+	}
+	if classes := m.closedComponents(func(s *State) []*Action { return s.actions }); len(classes) > 1 {
+		return report, &NotWeaklyCommunicatingError{classes}
+	}
+	if len(m.states) == 0 {
+		return report, nil
+	}
+	opts := &rvi.opts
+	w := make([]float64, len(m.states))
+	span := math.Inf(1)
+	var k int
+	for k = 0; k < opts.MaxIterations; k++ {
+		if k % ctxCheckInterval == 0 {
+			if ctx.Err() != nil {
+				report.Iterations[0] = k
+				report.Residual = span
+				return report, ctx.Err()
+			}
+			if rvi.progress != nil && k > 0 {
+				rvi.progress(Progress{Stage: 0, Iterations: k, Threshold: opts.MinTDError, Residual: span})
+			}
+		}
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for stateIdx := range m.states {
+			s := &m.states[stateIdx]
+			maxQ := math.Inf(-1)
+			for _, a := range s.actions {
+				q := 0.0
+				for _, tr := range a.transitions {
+					q += tr.p * (tr.r + rvi.Bias[tr.state.index])
+				}
+				rvi.Q[a.index] = q
+				maxQ = math.Max(maxQ, q)
+			}
+			w[stateIdx] = rvi.tau * maxQ + (1 - rvi.tau) * rvi.Bias[stateIdx]
+			diff := w[stateIdx] - rvi.Bias[stateIdx]
+			lo = math.Min(lo, diff)
+			hi = math.Max(hi, diff)
+		}
+		// the gain lies between the bounds of the update divided by tau
+		span = (hi - lo) / rvi.tau
+		rvi.Gain = (hi + lo) / 2 / rvi.tau
+		offset := w[rvi.ref]
+		for i := range w {
+			rvi.Bias[i] = w[i] - offset
+		}
+		if span < opts.MinTDError {
+			k++
+			break
+		}
+	}
+	report.Iterations[0] = k
+	report.Residual = span
+	report.HitMaxIterations = span >= opts.MinTDError
+	return report, nil
+}
+
+// UpdatePolicy updates the deterministic policy which is greedy in Q at every state.
+func (rvi *RelativeValueIterator) UpdatePolicy() {
+	updateGreedyPolicy(rvi.model, rvi.Q, rvi.Policy, func(s *State) []*Action { return s.actions })
+}
+
+// RecurrentClasses returns the state IDs of each recurrent class under the current policy.
+// More than one class means that the policy is multichain.
+func (rvi *RelativeValueIterator) RecurrentClasses() [][]int {
+	return rvi.model.closedComponents(func(s *State) []*Action {
+		actions := make([]*Action, 0, 1)
+		for _, a := range s.actions {
+			if rvi.Policy[a.index] > 0 {
+				actions = append(actions, a)
+			}
+		}
+		return actions
+	})
+}
+
+func (rvi *RelativeValueIterator) String() string {
+	s := fmt.Sprintf("Gain: %v\n", rvi.Gain)
+	s += fmt.Sprintf("Bias: %v\n", rvi.Bias)
+	s += fmt.Sprintf("Q: %v\n", rvi.Q)
+	s += fmt.Sprintf("Policy: %v\n", rvi.Policy)
+	return s
+}
@@ -0,0 +1,82 @@
+package mdp
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRelativeValueIteration(t *testing.T) {
+	// a patrol cycle 0 -> 1 -> 2 -> 0 earns 2 per step, and staying at 0 earns 1.5.
+	cm, _ := NewModel([]int{0, 1, 2}, []StateTransition{
+		{0, 1, 2},
+		{0, 0, 1.5},
+		{1, 2, 2},
+		{2, 0, 2},
+	})
+	rvi := NewRelativeValueIterator(cm)
+	report, err := rvi.RunRelativeValueIteration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.HitMaxIterations {
+		t.Errorf("unexpected cap: %v", report)
+	}
+	if math.Abs(rvi.Gain - 2) > 1e-3 {
+		t.Errorf("gain: got %.4f, want 2", rvi.Gain)
+	}
+	rvi.UpdatePolicy()
+	patrol, _ := cm.ActionByID(0, 1)
+	if rvi.Policy[patrol.Index()] != 1 {
+		t.Errorf("policy: got %v", rvi.Policy)
+	}
+	if classes := rvi.RecurrentClasses(); len(classes) != 1 || len(classes[0]) != 3 {
+		t.Errorf("recurrent classes: got %v", classes)
+	}
+	for i := range cm.states {
+		s := &cm.states[i]
+		maxQ := math.Inf(-1)
+		for _, a := range s.actions {
+			maxQ = math.Max(maxQ, rvi.Q[a.index])
+		}
+		if math.Abs(rvi.Gain + rvi.Bias[i] - maxQ) > 1e-3 {
+			t.Errorf("state %d: gain + bias = %.4f, max Q = %.4f", s.id, rvi.Gain + rvi.Bias[i], maxQ)
+		}
+	}
+
+	// 0 stays or moves to 1 with probability 0.5, and 1 returns to 0 earning 4.
+	sm, _ := NewStochasticModel([]int{0, 1}, []StochasticTransition{
+		{FromID: 0, Outcomes: []Outcome{{0, 0.5}, {1, 0.5}}},
+		{FromID: 1, Outcomes: []Outcome{{0, 1}}, Reward: 4},
+	})
+	rvi = NewRelativeValueIterator(sm)
+	if _, err := rvi.RunRelativeValueIteration(); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(rvi.Gain - 4.0 / 3.0) > 1e-3 {
+		t.Errorf("stochastic gain: got %.4f, want 1.3333", rvi.Gain)
+	}
+}
+
+func TestNotWeaklyCommunicating(t *testing.T) {
+	mm, _ := NewModel([]int{0, 1, 2, 3, 4}, []StateTransition{
+		{0, 1, 1},
+		{1, 0, 1},
+		{2, 3, 1},
+		{3, 2, 1},
+		{4, 0, 0},
+		{4, 2, 0},
+	})
+	rvi := NewRelativeValueIterator(mm)
+	_, err := rvi.RunRelativeValueIteration()
+	var ne *NotWeaklyCommunicatingError
+	if !errors.As(err, &ne) || len(ne.Classes) != 2 {
+		t.Errorf("got %v, want *NotWeaklyCommunicatingError with 2 classes", err)
+	}
+
+	dm, _ := NewModel([]int{0, 1}, []StateTransition{{0, 1, 1}})
+	rvi = NewRelativeValueIterator(dm)
+	if _, err := rvi.RunRelativeValueIteration(); !errors.Is(err, ErrNoAction) {
+		t.Errorf("got %v, want *DeadEndError", err)
+	}
+}
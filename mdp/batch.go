@@ -0,0 +1,142 @@
+package mdp
+
+import (
+	"context"
+	"sync"
+	"github.com/misteroda/go-rl/base"
+)
+
+// GoalSolution is a solution of a single goal.
+// V and Policy are stored in float32 to save memory over many goals.
+type GoalSolution struct {
+	GoalID int
+	V []float32
+	Policy []float32
+	Report ConvergenceReport
+}
+
+// BatchSolver solves one Model for many absorbing goals with a pool of workers.
+// Each worker owns a ValueIterator, so Q and float64 buffers are shared among the goals it solves.
+type BatchSolver struct {
+	model *Model
+	numWorkers int
+	alpha float64
+	gamma float64
+	warmStart bool
+	opts Options
+}
+
+// NewBatchSolver constructs a BatchSolver instance from a given Model.
+func NewBatchSolver(model *Model, numWorkers int) *BatchSolver {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	return &BatchSolver{
+		model: model,
+		numWorkers: numWorkers,
+		gamma: 1.0,
+		opts: DefaultOptions(),
+	}
+}
+
+// SetAlpha sets a value of alpha for every goal.
+func (b *BatchSolver) SetAlpha(alpha float64) {
+	b.alpha = alpha
+}
+
+// SetGamma sets a discount factor for every goal.
+func (b *BatchSolver) SetGamma(gamma float64) {
+	b.gamma = gamma
+}
+
+// SetOptions sets convergence settings for every goal.
+// It returns *InvalidOptionError if they are invalid.
+func (b *BatchSolver) SetOptions(opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	b.opts = opts
+	return nil
+}
+
+// SetWarmStart sets whether each goal is warm-started from the solution of the previous goal.
+// Goals are split into contiguous chunks, one per worker,
+// so neighboring goals should be adjacent in the list given to Solve.
+func (b *BatchSolver) SetWarmStart(warmStart bool) {
+	b.warmStart = warmStart
+}
+
+// Solve solves the Model for each of given goal IDs
+// and returns the solutions in the same order.
+func (b *BatchSolver) Solve(goalIDs []int) ([]GoalSolution, error) {
+	return b.SolveContext(context.Background(), goalIDs)
+}
+
+// SolveContext runs Solve until all goals are solved or ctx is done.
+func (b *BatchSolver) SolveContext(ctx context.Context, goalIDs []int) ([]GoalSolution, error) {
+	for _, id := range goalIDs {
+		if _, ok := b.model.StateOf[id]; !ok {
+			return nil, &UnknownStateError{id}
+		}
+	}
+	solutions := make([]GoalSolution, len(goalIDs))
+	chunk := (len(goalIDs) + b.numWorkers - 1) / b.numWorkers
+	errs := make([]error, b.numWorkers)
+	wg := sync.WaitGroup{}
+	for w := 0; w < b.numWorkers && w * chunk < len(goalIDs); w++ {
+		lo := w * chunk
+		hi := lo + chunk
+		if hi > len(goalIDs) {
+			hi = len(goalIDs)
+		}
+		wg.Add(1)
+		go func(w, lo, hi int) {
+			defer wg.Done()
+			errs[w] = b.solveChunk(ctx, goalIDs[lo:hi], solutions[lo:hi])
+		}(w, lo, hi)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return solutions, nil
+}
+
+func (b *BatchSolver) solveChunk(ctx context.Context, goalIDs []int, solutions []GoalSolution) error {
+	vi := NewValueIteratorWithOptions(b.model, b.opts)
+	for k, goalID := range goalIDs {
+		if k == 0 || !b.warmStart {
+			vi.Init()
+		} else {
+			vi.InitAbsorbingState()
+			base.Vector(vi.Policy).Fill(0.0)
+		}
+		vi.SetAlpha(b.alpha)
+		vi.SetGamma(b.gamma)
+		if err := vi.SetAbsorbingState(goalID); err != nil {
+			return err
+		}
+		report, err := vi.RunValueIterationContext(ctx)
+		if err != nil {
+			return err
+		}
+		vi.UpdatePolicy()
+		solutions[k] = GoalSolution{
+			GoalID: goalID,
+			V: toFloat32(vi.V),
+			Policy: toFloat32(vi.Policy),
+			Report: report,
+		}
+	}
+	return nil
+}
+
+func toFloat32(x []float64) []float32 {
+	y := make([]float32, len(x))
+	for i, v := range x {
+		y[i] = float32(v)
+	}
+	return y
+}
@@ -0,0 +1,41 @@
+package mdp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBatchSolver(t *testing.T) {
+	gm := newGridModel(6, 5)
+	goalIDs := []int{0, 1, 2, 7, 12, 29}
+	for _, warmStart := range []bool{false, true} {
+		b := NewBatchSolver(gm, 4)
+		b.SetWarmStart(warmStart)
+		solutions, err := b.Solve(goalIDs)
+		if err != nil {
+			t.Fatalf("Solve: %v", err)
+		}
+		for k, goalID := range goalIDs {
+			want := NewValueIterator(gm)
+			run(want, goalID)
+			got := solutions[k]
+			if got.GoalID != goalID {
+				t.Errorf("@%d: got goal %d, want %d", k, got.GoalID, goalID)
+			}
+			for i := range want.V {
+				if math.Abs(float64(got.V[i]) - want.V[i]) > 1e-2 {
+					t.Errorf("warm=%t goal=%d V@%d: got %.3f, want %.3f", warmStart, goalID, i, got.V[i], want.V[i])
+				}
+			}
+			for i := range want.Policy {
+				if float64(got.Policy[i]) != want.Policy[i] {
+					t.Errorf("warm=%t goal=%d Policy@%d: got %.3f, want %.3f", warmStart, goalID, i, got.Policy[i], want.Policy[i])
+				}
+			}
+		}
+	}
+
+	if _, err := NewBatchSolver(gm, 2).Solve([]int{100}); err == nil {
+		t.Errorf("Solve: want *UnknownStateError")
+	}
+}
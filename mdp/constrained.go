@@ -0,0 +1,78 @@
+package mdp
+
+// NumCosts returns a number of auxiliary costs of each action.
+func (m *Model) NumCosts() int {
+	return m.numCosts
+}
+
+// ActionCosts returns the k-th auxiliary cost of all actions.
+func (m *Model) ActionCosts(k int) ([]float64, error) {
+	if k < 0 || k >= m.numCosts {
+		return nil, &UnknownCostError{k}
+	}
+	costs := make([]float64, len(m.actions))
+	for i := range m.actions {
+		if m.actions[i].costs != nil {
+			costs[i] = m.actions[i].costs[k]
+		}
+	}
+	return costs, nil
+}
+
+// SetCosts replaces auxiliary costs of all actions,
+// where costs[k] is the k-th cost of each action, e.g. for a Model constructed by NewModel.
+func (m *Model) SetCosts(costs [][]float64) error {
+	for _, c := range costs {
+		if len(c) != len(m.actions) {
+			return &DimensionMismatchError{Got: len(c), Want: len(m.actions)}
+		}
+	}
+	m.numCosts = len(costs)
+	for i := range m.actions {
+		a := &m.actions[i]
+		a.costs = nil
+		if m.numCosts == 0 || a.state == nil { continue }
+		a.costs = make([]float64, m.numCosts)
+		for k, c := range costs {
+			a.costs[k] = c[i]
+		}
+	}
+	return nil
+}
+
+// ExpectedCosts returns the expected total of each auxiliary cost
+// under a given state-action visitation frequency distribution.
+func (m *Model) ExpectedCosts(actionDist []float64) ([]float64, error) {
+	if len(actionDist) != len(m.actions) {
+		return nil, &DimensionMismatchError{Got: len(actionDist), Want: len(m.actions)}
+	}
+	expected := make([]float64, m.numCosts)
+	for i, d := range actionDist {
+		for k, c := range m.actions[i].costs {
+			expected[k] += d * c
+		}
+	}
+	return expected, nil
+}
+
+// SetBudget adds a constraint that the expected total of the k-th auxiliary cost
+// does not exceed budget to the dual program.
+// SolveDual then returns a possibly randomized policy meeting every budget in expectation,
+// and Multipliers hold the Lagrange multipliers of the budgets.
+func (lps *LPSolver) SetBudget(k int, budget float64) error {
+	costs, err := lps.model.ActionCosts(k)
+	if err != nil {
+		return err
+	}
+	return lps.AddOccupancyConstraint(OccupancyConstraint{Coef: costs, Sense: LessEqual, Bound: budget})
+}
+
+// ExpectedCosts returns the expected total of each auxiliary cost
+// of the current policy from initialStateDist, using its state-action visitation.
+func (lps *LPSolver) ExpectedCosts(initialStateDist []float64) ([]float64, error) {
+	if len(initialStateDist) != len(lps.model.states) {
+		return nil, &DimensionMismatchError{Got: len(initialStateDist), Want: len(lps.model.states)}
+	}
+	_, actionDist := newPolicyVisitor(lps.model, lps.Policy, lps.isAbsorbing, lps.gamma).StateActionVisitation(initialStateDist)
+	return lps.model.ExpectedCosts(actionDist)
+}
@@ -0,0 +1,80 @@
+package mdp
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestConstrainedLPSolver(t *testing.T) {
+	// 0 reaches 2 directly on a toll road or through 1 for free.
+	cm, _ := NewStochasticModel([]int{0, 1, 2}, []StochasticTransition{
+		{FromID: 0, Outcomes: []Outcome{{2, 1}}, Reward: -1, Costs: []float64{1, 0.5}},
+		{FromID: 0, Outcomes: []Outcome{{1, 1}}, Reward: -1},
+		{FromID: 1, Outcomes: []Outcome{{2, 1}}, Reward: -1, Costs: []float64{0, 0.5}},
+	})
+	if cm.NumCosts() != 2 {
+		t.Fatalf("NumCosts: got %d, want 2", cm.NumCosts())
+	}
+	initialStateDist := []float64{1, 0, 0}
+	lps := NewLPSolver(cm)
+	lps.SetAbsorbingState(2)
+	if err := lps.SetBudget(0, 0.4); err != nil {
+		t.Fatal(err)
+	}
+	if err := lps.SetBudget(2, 1); err == nil {
+		t.Errorf("SetBudget(2): got nil, want *UnknownCostError")
+	}
+	if err := lps.SolveDual(initialStateDist); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(lps.Policy[0] - 0.4) > 1e-9 || math.Abs(lps.Policy[1] - 0.6) > 1e-9 {
+		t.Errorf("policy: got %v, want [0.4 0.6 1]", lps.Policy)
+	}
+	if len(lps.Multipliers) != 1 || math.Abs(lps.Multipliers[0] - 1) > 1e-9 {
+		t.Errorf("multipliers: got %v, want [1]", lps.Multipliers)
+	}
+	costs, err := lps.ExpectedCosts(initialStateDist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCosts := []float64{0.4, 0.5}
+	for k := range wantCosts {
+		if math.Abs(costs[k] - wantCosts[k]) > 1e-3 {
+			t.Errorf("cost %d: got %.4f, want %.4f", k, costs[k], wantCosts[k])
+		}
+	}
+
+	// auxiliary costs survive encoding
+	data, _ := json.Marshal(cm)
+	var fromJSON Model
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	cm.WriteTo(&buf)
+	fromBinary, err := ReadModel(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, loaded := range []*Model{&fromJSON, fromBinary} {
+		for k := 0; k < cm.NumCosts(); k++ {
+			got, _ := loaded.ActionCosts(k)
+			want, _ := cm.ActionCosts(k)
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("cost %d of action %d: got %.3f, want %.3f", k, i, got[i], want[i])
+				}
+			}
+		}
+	}
+
+	dm, _ := NewModel([]int{0, 1}, []StateTransition{{0, 1, -1}})
+	if err := dm.SetCosts([][]float64{{2}}); err != nil || dm.NumCosts() != 1 {
+		t.Errorf("SetCosts: got %v with %d costs", err, dm.NumCosts())
+	}
+	if err := dm.SetCosts([][]float64{{2, 3}}); err == nil {
+		t.Errorf("SetCosts: got nil, want *DimensionMismatchError")
+	}
+}
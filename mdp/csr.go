@@ -0,0 +1,445 @@
+package mdp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"github.com/misteroda/go-rl/base"
+)
+
+// ErrCSROverflow is returned when states, actions or outcomes do not fit in int32 CSR indices.
+var ErrCSROverflow = errors.New("mdp: model too large for int32 CSR indices")
+
+// CSRModel is a Model stored in compressed sparse row format.
+// Actions of a state, outcomes of an action and incoming outcomes of a state
+// are contiguous ranges of int32 index arrays, which saves memory and
+// keeps sweeps cache-friendly on large models.
+// Action indices are the same as the Model built from the same transitions,
+// so Q, Policy and maxent.Feature are interchangeable between the two.
+type CSRModel struct {
+	StateOf map[int]int32 // state ID => state index
+	stateIDs []int
+	numActions int
+
+	// actions of state s are [actionPtr[s], actionPtr[s+1]) in CSR order
+	actionPtr []int32
+	actionIndex []int32 // CSR position => action index
+	actionState []int32 // CSR position => state index
+	reward []float64 // CSR position => reward
+
+	// outcomes of CSR position k are [outcomePtr[k], outcomePtr[k+1])
+	outcomePtr []int32
+	outcomeState []int32
+	outcomeProb []float64
+
+	// incoming outcomes of state s are [inPtr[s], inPtr[s+1])
+	inPtr []int32
+	inAction []int32 // CSR position of the action
+	inProb []float64
+}
+
+// NewCSRModel constructs a CSRModel instance directly from transitions
+// without building a Model, and returns a pointer to it.
+// Transitions are dropped and reported as NewStochasticModel does.
+// Auxiliary costs of transitions are not kept.
+// It returns ErrCSROverflow if the counts of states, actions or outcomes exceed math.MaxInt32.
+func NewCSRModel(stateIDs []int, stochasticTransitions []StochasticTransition) (*CSRModel, error) {
+	n := len(stateIDs)
+	if n >= math.MaxInt32 || len(stochasticTransitions) > math.MaxInt32 {
+		return nil, ErrCSROverflow
+	}
+	StateOf := make(map[int]int32, n)
+	for i, id := range stateIDs {
+		StateOf[id] = int32(i)
+	}
+
+	// count actions and outcomes per state
+	var dangling []DanglingTransition
+	valid := make([]bool, len(stochasticTransitions))
+	actionPtr := make([]int32, n + 1)
+	inPtr := make([]int32, n + 1)
+	numCSRActions, numOutcomes := 0, 0
+	for i, st := range stochasticTransitions {
+		unknownIDs := findUnknownCSRIDs(StateOf, st)
+		if len(unknownIDs) > 0 || len(st.Outcomes) == 0 {
+			dangling = append(dangling, DanglingTransition{
+				Index: i,
+				FromID: st.FromID,
+				UnknownIDs: unknownIDs,
+			})
+			continue
+		}
+		valid[i] = true
+		actionPtr[StateOf[st.FromID] + 1]++
+		for _, o := range st.Outcomes {
+			inPtr[StateOf[o.ToID] + 1]++
+		}
+		numCSRActions++
+		numOutcomes += len(st.Outcomes)
+	}
+	if numOutcomes > math.MaxInt32 {
+		return nil, ErrCSROverflow
+	}
+	for s := 0; s < n; s++ {
+		actionPtr[s + 1] += actionPtr[s]
+		inPtr[s + 1] += inPtr[s]
+	}
+
+	// place actions in CSR order
+	m := &CSRModel{
+		StateOf: StateOf,
+		stateIDs: append([]int(nil), stateIDs...),
+		numActions: len(stochasticTransitions),
+		actionPtr: actionPtr,
+		actionIndex: make([]int32, numCSRActions),
+		actionState: make([]int32, numCSRActions),
+		reward: make([]float64, numCSRActions),
+		outcomePtr: make([]int32, numCSRActions + 1),
+		outcomeState: make([]int32, 0, numOutcomes),
+		outcomeProb: make([]float64, 0, numOutcomes),
+		inPtr: inPtr,
+		inAction: make([]int32, numOutcomes),
+		inProb: make([]float64, numOutcomes),
+	}
+	next := make([]int32, n)
+	copy(next, actionPtr[:n])
+	for i, st := range stochasticTransitions {
+		if !valid[i] { continue }
+		s := StateOf[st.FromID]
+		k := next[s]
+		next[s]++
+		m.actionIndex[k] = int32(i)
+		m.actionState[k] = s
+		m.reward[k] = st.Reward
+	}
+	inNext := make([]int32, n)
+	copy(inNext, inPtr[:n])
+	for k := range m.actionIndex {
+		m.outcomePtr[k] = int32(len(m.outcomeState))
+		for _, o := range stochasticTransitions[m.actionIndex[k]].Outcomes {
+			to := StateOf[o.ToID]
+			m.outcomeState = append(m.outcomeState, to)
+			m.outcomeProb = append(m.outcomeProb, o.Probability)
+			m.inAction[inNext[to]] = int32(k)
+			m.inProb[inNext[to]] = o.Probability
+			inNext[to]++
+		}
+	}
+	m.outcomePtr[numCSRActions] = int32(len(m.outcomeState))
+
+	if len(dangling) > 0 {
+		return m, &DanglingTransitionError{Transitions: dangling}
+	}
+	return m, nil
+}
+
+func findUnknownCSRIDs(StateOf map[int]int32, st StochasticTransition) (ids []int) {
+	if _, ok := StateOf[st.FromID]; !ok {
+		ids = append(ids, st.FromID)
+	}
+	for _, o := range st.Outcomes {
+		if _, ok := StateOf[o.ToID]; !ok {
+			ids = append(ids, o.ToID)
+		}
+	}
+	return
+}
+
+// CSR converts the Model into a CSRModel keeping state and action indices.
+// It returns nil if the Model overflows int32 CSR indices.
+func (m *Model) CSR() *CSRModel {
+	csr, _ := NewCSRModel(m.StateIDs(), m.StochasticTransitions())
+	return csr
+}
+
+// NumStates returns a number of states in MDP.
+func (m *CSRModel) NumStates() int {
+	return len(m.stateIDs)
+}
+
+// NumActions returns a number of actions in MDP.
+func (m *CSRModel) NumActions() int {
+	return m.numActions
+}
+
+// UpdateReward update the reward of all actions.
+func (m *CSRModel) UpdateReward(reward []float64) error {
+	if len(reward) != m.numActions {
+		return &DimensionMismatchError{Got: len(reward), Want: m.numActions}
+	}
+	for k, i := range m.actionIndex {
+		m.reward[k] = reward[i]
+	}
+	return nil
+}
+
+// CSRValueIterator represents Value Iteration algorithm on a CSRModel.
+// It behaves the same as ValueIterator.
+// Like ValueIterator, it optimizes rewards only, so auxiliary costs need a Model and LPSolver.
+type CSRValueIterator struct {
+	model *CSRModel
+	V []float64 // state values
+	Q []float64 // state-action values
+	Policy []float64
+	absorbing
+	alpha float64 // temperature parameter for softmax operator
+	gamma float64 // discount factor
+	convergence
+	progress ProgressFunc
+}
+
+// NewCSRValueIterator constructs a CSRValueIterator instance from a given CSRModel.
+func NewCSRValueIterator(model *CSRModel) *CSRValueIterator {
+	return NewCSRValueIteratorWithOptions(model, DefaultOptions())
+}
+
+// NewCSRValueIteratorWithOptions constructs a CSRValueIterator instance
+// from a given CSRModel and convergence settings.
+func NewCSRValueIteratorWithOptions(model *CSRModel, opts Options) *CSRValueIterator {
+	V := make([]float64, model.NumStates())
+	return &CSRValueIterator{
+		model: model,
+		V: V,
+		Q: make([]float64, model.NumActions()),
+		Policy: make([]float64, model.NumActions()),
+		absorbing: newAbsorbing(model, model.NumStates(), V),
+		gamma: 1.0,
+		convergence: convergence{opts},
+	}
+}
+
+// SetAlpha sets a value of alpha.
+func (vi *CSRValueIterator) SetAlpha(alpha float64) {
+	vi.alpha = alpha
+}
+
+// SetGamma sets a discount factor.
+func (vi *CSRValueIterator) SetGamma(gamma float64) {
+	vi.gamma = gamma
+}
+
+// SetProgressFunc sets a hook which is called at the end of each annealing stage.
+func (vi *CSRValueIterator) SetProgressFunc(progress ProgressFunc) {
+	vi.progress = progress
+}
+
+// Init initializes CSRValueIterator instance.
+func (vi *CSRValueIterator) Init() {
+	base.Vector(vi.V).Fill(0.0)
+	base.Vector(vi.Q).Fill(0.0)
+	base.Vector(vi.Policy).Fill(0.0)
+	vi.InitAbsorbingState()
+	vi.alpha = 0.0
+	vi.gamma = 1.0
+}
+
+// actionRange returns the CSR positions of the action space of a given state.
+func (vi *CSRValueIterator) actionRange(s int32) (int32, int32) {
+	if vi.isAbsorbing[s] {
+		return 0, 0
+	}
+	return vi.model.actionPtr[s], vi.model.actionPtr[s + 1]
+}
+
+// RunValueIteration runs Value Iteration algorithm and updates V and Q.
+// It starts from the current V, so a previous solution warm-starts it.
+func (vi *CSRValueIterator) RunValueIteration() ConvergenceReport {
+	report, _ := vi.RunValueIterationContext(context.Background())
+	return report
+}
+
+// RunValueIterationContext runs Value Iteration algorithm and updates V and Q
+// until convergence or cancellation of ctx.
+// It returns *InvalidOptionError if the convergence settings are invalid.
+func (vi *CSRValueIterator) RunValueIterationContext(ctx context.Context) (ConvergenceReport, error) {
+	m := vi.model
+	opts := &vi.opts
+	if err := opts.Validate(); err != nil {
+		return ConvergenceReport{}, err
+	}
+	n := m.NumStates()
+	pq := NewPriorityQueue(n)
+	report := ConvergenceReport{Iterations: make([]int, opts.NumAnnealing)}
+	tdThreshold := opts.initialThreshold(opts.MinTDError)
+	for i := 0; i < opts.NumAnnealing; i++ {
+		for s := 0; s < n; s++ {
+			td := vi.bellmanBackup(int32(s))
+			if td > tdThreshold && opts.hasRoom(pq) {
+				pq.Push(s, td)
+			}
+		}
+		var j int
+		var td float64
+		for j = 0; j < opts.MaxIterations && pq.Size() > 0; j++ {
+			if j % ctxCheckInterval == 0 && ctx.Err() != nil {
+				report.Iterations[i] = j
+				return report, ctx.Err()
+			}
+			idx, _ := pq.Pop()
+			for k := m.inPtr[idx]; k < m.inPtr[idx + 1]; k++ {
+				s := m.actionState[m.inAction[k]]
+				td = vi.bellmanBackup(s)
+				if td > tdThreshold && opts.hasRoom(pq) {
+					pq.Push(int(s), td)
+				}
+			}
+		}
+		report.Iterations[i] = j
+		if pq.Size() > 0 {
+			report.HitMaxIterations = true
+		}
+		if vi.progress != nil {
+			vi.progress(Progress{Stage: i, Iterations: j, Threshold: tdThreshold, Residual: td, Remaining: pq.Size()})
+		}
+		tdThreshold *= opts.AnnealingRate
+	}
+	for s := 0; s < n; s++ {
+		report.Residual = math.Max(report.Residual, vi.bellmanBackup(int32(s)))
+	}
+	return report, nil
+}
+
+func (vi *CSRValueIterator) bellmanBackup(s int32) (tdError float64) {
+	m := vi.model
+	lo, hi := vi.actionRange(s)
+	if lo == hi { return }
+	maxQ := math.Inf(-1)
+	for k := lo; k < hi; k++ {
+		q := 0.0
+		for o := m.outcomePtr[k]; o < m.outcomePtr[k + 1]; o++ {
+			q += m.outcomeProb[o] * (m.reward[k] + vi.gamma * vi.V[m.outcomeState[o]])
+		}
+		vi.Q[m.actionIndex[k]] = q
+		maxQ = math.Max(maxQ, q)
+	}
+	v := maxQ
+	if vi.alpha != 0 {
+		lse := 0.0
+		for k := lo; k < hi; k++ {
+			lse += math.Exp((vi.Q[m.actionIndex[k]] - maxQ) / vi.alpha)
+		}
+		v = vi.alpha * math.Log(lse) + maxQ
+	}
+	tdError = math.Abs(v - vi.V[s])
+	vi.V[s] = v
+	return
+}
+
+// UpdatePolicy updates policy based on current state-action values.
+func (vi *CSRValueIterator) UpdatePolicy() {
+	m := vi.model
+	for s := int32(0); s < int32(m.NumStates()); s++ {
+		lo, hi := vi.actionRange(s)
+		if lo == hi { continue }
+		best := lo
+		for k := lo; k < hi; k++ {
+			if vi.Q[m.actionIndex[best]] < vi.Q[m.actionIndex[k]] {
+				best = k
+			}
+		}
+		if vi.alpha == 0 {
+			for k := lo; k < hi; k++ {
+				vi.Policy[m.actionIndex[k]] = 0
+			}
+			vi.Policy[m.actionIndex[best]] = 1
+			continue
+		}
+		maxQ := vi.Q[m.actionIndex[best]]
+		z := 0.0
+		for k := lo; k < hi; k++ {
+			a := m.actionIndex[k]
+			vi.Policy[a] = math.Exp((vi.Q[a] - maxQ) / vi.alpha)
+			z += vi.Policy[a]
+		}
+		for k := lo; k < hi; k++ {
+			vi.Policy[m.actionIndex[k]] /= z
+		}
+	}
+}
+
+func (vi *CSRValueIterator) computeStateDist(s int32, actionDist []float64) float64 {
+	m := vi.model
+	d := 0.0
+	for k := m.inPtr[s]; k < m.inPtr[s + 1]; k++ {
+		pos := m.inAction[k]
+		if vi.isAbsorbing[m.actionState[pos]] { continue }
+		d += actionDist[m.actionIndex[pos]] * m.inProb[k]
+	}
+	return vi.gamma * d
+}
+
+// StateActionVisitation computes state-action visitation frequency distribution based on the current policy.
+// If gamma is less than 1, the result is the discounted occupancy.
+func (vi *CSRValueIterator) StateActionVisitation(initialStateDist []float64) ([]float64, []float64) {
+	stateDist, actionDist, _ := vi.StateActionVisitationContext(context.Background(), initialStateDist)
+	return stateDist, actionDist
+}
+
+// StateActionVisitationContext computes state-action visitation frequency distribution
+// based on the current policy until convergence or cancellation of ctx.
+// It returns *InvalidOptionError if the convergence settings are invalid.
+func (vi *CSRValueIterator) StateActionVisitationContext(ctx context.Context, initialStateDist []float64) ([]float64, []float64, error) {
+	m := vi.model
+	opts := &vi.opts
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+	stateDist := make([]float64, m.NumStates())
+	copy(stateDist, initialStateDist)
+	actionDist := make([]float64, m.NumActions())
+
+	pq := NewPriorityQueue(len(stateDist))
+	// visit propagates the visitation of a state to its successors
+	// and passes each successor with the change of its visitation to push.
+	visit := func(s int32, threshold float64, push func(next int32, sd float64)) {
+		lo, hi := vi.actionRange(s)
+		if lo == hi || stateDist[s] < threshold { return }
+		for k := lo; k < hi; k++ {
+			a := m.actionIndex[k]
+			actionDist[a] = stateDist[s] * vi.Policy[a]
+			for o := m.outcomePtr[k]; o < m.outcomePtr[k + 1]; o++ {
+				next := m.outcomeState[o]
+				sd := stateDist[next]
+				stateDist[next] = initialStateDist[next] + vi.computeStateDist(next, actionDist)
+				push(next, math.Abs(sd - stateDist[next]))
+			}
+		}
+	}
+	sdThreshold := opts.initialThreshold(opts.MinSDError)
+	for i := 0; i < opts.NumAnnealing; i++ {
+		if i == 0 {
+			for idx, sd := range initialStateDist {
+				if sd > 0 {
+					pq.Push(idx, sd)
+				}
+			}
+		} else {
+			for s := int32(0); s < int32(len(stateDist)); s++ {
+				visit(s, sdThreshold, func(next int32, sd float64) {
+					if sd > sdThreshold && opts.hasRoom(pq) {
+						pq.Push(int(s), sd)
+					}
+				})
+			}
+		}
+		var j int
+		var lastSD float64
+		for j = 0; j < opts.MaxIterations && pq.Size() > 0; j++ {
+			if j % ctxCheckInterval == 0 && ctx.Err() != nil {
+				return stateDist, actionDist, ctx.Err()
+			}
+			idx, _ := pq.Pop()
+			visit(int32(idx), sdThreshold, func(next int32, sd float64) {
+				lastSD = sd
+				if sd > sdThreshold && opts.hasRoom(pq) {
+					pq.Push(int(next), sd)
+				}
+			})
+		}
+		if vi.progress != nil {
+			vi.progress(Progress{Stage: i, Iterations: j, Threshold: sdThreshold, Residual: lastSD, Remaining: pq.Size()})
+		}
+		sdThreshold *= opts.AnnealingRate
+	}
+	return stateDist, actionDist, nil
+}
@@ -0,0 +1,90 @@
+package mdp
+
+import (
+	"testing"
+)
+
+func TestCSRValueIteration(t *testing.T) {
+	gm := newGridModel(7, 5)
+	csr := gm.CSR()
+	if csr.NumStates() != gm.NumStates() || csr.NumActions() != gm.NumActions() {
+		t.Fatalf("got %d states and %d actions", csr.NumStates(), csr.NumActions())
+	}
+	initialStateDist := make([]float64, gm.NumStates())
+	initialStateDist[0] = 1
+	for _, alpha := range []float64{0, 0.5} {
+		want := NewValueIterator(gm)
+		want.SetAlpha(alpha)
+		want.SetAbsorbingState(17)
+		want.RunValueIteration()
+		want.UpdatePolicy()
+		wantStateDist, wantActionDist := want.StateActionVisitation(initialStateDist)
+
+		got := NewCSRValueIterator(csr)
+		stages := 0
+		got.SetProgressFunc(func(p Progress) { stages++ })
+		got.SetAlpha(alpha)
+		got.SetAbsorbingState(17)
+		got.RunValueIteration()
+		got.UpdatePolicy()
+		gotStateDist, gotActionDist := got.StateActionVisitation(initialStateDist)
+		if stages != 2 * numAnnealing {
+			t.Errorf("alpha=%.1f: got %d progress calls, want %d", alpha, stages, 2 * numAnnealing)
+		}
+
+		for i := range want.V {
+			if got.V[i] != want.V[i] || gotStateDist[i] != wantStateDist[i] {
+				t.Errorf("alpha=%.1f @%d: got %.3f/%.3f, want %.3f/%.3f",
+					alpha, i, got.V[i], gotStateDist[i], want.V[i], wantStateDist[i])
+			}
+		}
+		for i := range want.Q {
+			if got.Q[i] != want.Q[i] || got.Policy[i] != want.Policy[i] || gotActionDist[i] != wantActionDist[i] {
+				t.Errorf("alpha=%.1f action@%d: got %.3f/%.3f/%.3f, want %.3f/%.3f/%.3f", alpha, i,
+					got.Q[i], got.Policy[i], gotActionDist[i], want.Q[i], want.Policy[i], wantActionDist[i])
+			}
+		}
+	}
+}
+
+func BenchmarkValueIteration(b *testing.B) {
+	gm := newGridModel(100, 100)
+	vi := NewValueIterator(gm)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vi.Init()
+		vi.SetAbsorbingState(0)
+		vi.RunValueIteration()
+	}
+}
+
+func BenchmarkCSRValueIteration(b *testing.B) {
+	csr := newGridModel(100, 100).CSR()
+	vi := NewCSRValueIterator(csr)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vi.Init()
+		vi.SetAbsorbingState(0)
+		vi.RunValueIteration()
+	}
+}
+
+func BenchmarkNewModel(b *testing.B) {
+	gm := newGridModel(100, 100)
+	ids, sts := gm.StateIDs(), gm.StochasticTransitions()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewStochasticModel(ids, sts)
+	}
+}
+
+func BenchmarkNewCSRModel(b *testing.B) {
+	gm := newGridModel(100, 100)
+	ids, sts := gm.StateIDs(), gm.StochasticTransitions()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewCSRModel(ids, sts)
+	}
+}
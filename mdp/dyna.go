@@ -0,0 +1,267 @@
+package mdp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// DynaAgent represents Dyna-Q with prioritized sweeping.
+// It learns an empirical model from the transitions it experiences in an Env,
+// and between real steps replays expected backups from that model
+// in the order of their priority, propagating changes back to predecessor actions.
+// Q is indexed by action index, as ValueIterator.Q is for a Model.
+type DynaAgent struct {
+	env Env
+	Q []float64 // state-action values
+	fromID []int // state ID of each action
+	counts []map[int]int // observed next state IDs of each action
+	numVisits []int // number of times each action is taken
+	rewardSum []float64 // sum of rewards of each action
+	predecessors map[int][]int // actions observed to reach each state ID
+	terminal map[int]bool // observed state IDs without actions
+	pq *PriorityQueue
+	epsilon float64
+	gamma float64 // discount factor
+	planningSteps int
+	threshold float64 // minimum priority to queue an action
+	maxSteps int
+	Steps int // number of real steps taken
+	rand *rand.Rand
+}
+
+// NewDynaAgent constructs a DynaAgent instance from a given Env and a number of actions.
+// By default it explores epsilon-greedily with epsilon 0.1
+// and makes 10 planning updates after each real step.
+func NewDynaAgent(env Env, numActions int) *DynaAgent {
+	da := &DynaAgent{
+		env: env,
+		Q: make([]float64, numActions),
+		fromID: make([]int, numActions),
+		counts: make([]map[int]int, numActions),
+		numVisits: make([]int, numActions),
+		rewardSum: make([]float64, numActions),
+		predecessors: make(map[int][]int),
+		terminal: make(map[int]bool),
+		pq: NewPriorityQueue(numActions),
+		epsilon: 0.1,
+		gamma: 1,
+		planningSteps: 10,
+		threshold: minTDError,
+		maxSteps: 10000,
+		rand: rand.New(rand.NewSource(rand.Int63())),
+	}
+	for i := range da.counts {
+		da.counts[i] = make(map[int]int)
+	}
+	return da
+}
+
+// SetRand sets the source of randomness of exploration, e.g. seeded for reproducibility.
+func (da *DynaAgent) SetRand(r *rand.Rand) {
+	da.rand = r
+}
+
+// SetEpsilon sets the probability of a random action.
+func (da *DynaAgent) SetEpsilon(epsilon float64) {
+	da.epsilon = epsilon
+}
+
+// SetGamma sets a discount factor.
+func (da *DynaAgent) SetGamma(gamma float64) {
+	da.gamma = gamma
+}
+
+// SetPlanningSteps sets the number of planning updates after each real step.
+func (da *DynaAgent) SetPlanningSteps(planningSteps int) {
+	da.planningSteps = planningSteps
+}
+
+// SetThreshold sets the minimum priority for an action to be queued for planning.
+func (da *DynaAgent) SetThreshold(threshold float64) {
+	da.threshold = threshold
+}
+
+// SetMaxSteps sets the maximum number of steps of an episode.
+func (da *DynaAgent) SetMaxSteps(maxSteps int) {
+	da.maxSteps = maxSteps
+}
+
+// value returns the greedy value of a state ID under Q.
+func (da *DynaAgent) value(stateID int) float64 {
+	if da.terminal[stateID] {
+		return 0
+	}
+	actions := da.env.Actions(stateID)
+	if len(actions) == 0 {
+		da.terminal[stateID] = true
+		return 0
+	}
+	v := math.Inf(-1)
+	for _, a := range actions {
+		v = math.Max(v, da.Q[a])
+	}
+	return v
+}
+
+// backup returns the expected Q of an action under the empirical model.
+func (da *DynaAgent) backup(a int) float64 {
+	n := float64(da.numVisits[a])
+	q := da.rewardSum[a] / n
+	for id, c := range da.counts[a] {
+		q += da.gamma * float64(c) / n * da.value(id)
+	}
+	return q
+}
+
+// prioritize queues an action with the change of its Q by a backup.
+func (da *DynaAgent) prioritize(a int) {
+	if p := math.Abs(da.backup(a) - da.Q[a]); p > da.threshold {
+		da.pq.Push(a, p)
+	}
+}
+
+// plan runs planning updates in the order of priority.
+func (da *DynaAgent) plan() {
+	for k := 0; k < da.planningSteps && da.pq.Size() > 0; k++ {
+		a, _ := da.pq.Pop()
+		da.Q[a] = da.backup(a)
+		for _, b := range da.predecessors[da.fromID[a]] {
+			da.prioritize(b)
+		}
+	}
+}
+
+func (da *DynaAgent) chooseAction(actions []int) int {
+	if da.rand.Float64() < da.epsilon {
+		return actions[da.rand.Intn(len(actions))]
+	}
+	best := actions[0]
+	for _, a := range actions {
+		if da.Q[a] > da.Q[best] {
+			best = a
+		}
+	}
+	return best
+}
+
+// observe updates the empirical model with a transition.
+func (da *DynaAgent) observe(stateID, a int, r float64, nextID int) {
+	if da.numVisits[a] == 0 {
+		da.fromID[a] = stateID
+	}
+	if _, ok := da.counts[a][nextID]; !ok {
+		da.predecessors[nextID] = append(da.predecessors[nextID], a)
+	}
+	da.counts[a][nextID]++
+	da.numVisits[a]++
+	da.rewardSum[a] += r
+}
+
+// RunEpisode runs an episode and returns the discounted return.
+// After each real step, Q of the action taken is updated from the empirical model
+// before the planning updates.
+func (da *DynaAgent) RunEpisode() (float64, error) {
+	stateID := da.env.Reset()
+	ret, discount := 0.0, 1.0
+	for k := 0; k < da.maxSteps; k++ {
+		actions := da.env.Actions(stateID)
+		if len(actions) == 0 {
+			break
+		}
+		a := da.chooseAction(actions)
+		nextID, r, done, err := da.env.Step(a)
+		if err != nil {
+			return ret, err
+		}
+		da.Steps++
+		ret += discount * r
+		discount *= da.gamma
+		da.observe(stateID, a, r, nextID)
+		// direct update, followed by planning from the predecessors it affects
+		da.Q[a] = da.backup(a)
+		for _, b := range da.predecessors[stateID] {
+			da.prioritize(b)
+		}
+		da.plan()
+		if done {
+			break
+		}
+		stateID = nextID
+	}
+	return ret, nil
+}
+
+// Train runs numEpisodes episodes and returns their returns.
+func (da *DynaAgent) Train(numEpisodes int) ([]float64, error) {
+	return da.TrainContext(context.Background(), numEpisodes)
+}
+
+// TrainContext runs Train until completion or cancellation of ctx.
+// ctx is checked at each episode.
+func (da *DynaAgent) TrainContext(ctx context.Context, numEpisodes int) ([]float64, error) {
+	returns := make([]float64, 0, numEpisodes)
+	for i := 0; i < numEpisodes; i++ {
+		if ctx.Err() != nil {
+			return returns, ctx.Err()
+		}
+		ret, err := da.RunEpisode()
+		if err != nil {
+			return returns, err
+		}
+		returns = append(returns, ret)
+	}
+	return returns, nil
+}
+
+// Greedy returns the index of the best action at a state, or -1 if it has no actions.
+func (da *DynaAgent) Greedy(stateID int) int {
+	actions := da.env.Actions(stateID)
+	if len(actions) == 0 {
+		return -1
+	}
+	best := actions[0]
+	for _, a := range actions {
+		if da.Q[a] > da.Q[best] {
+			best = a
+		}
+	}
+	return best
+}
+
+// Model returns the empirical Model of observed states and transitions
+// with maximum likelihood probabilities and mean rewards.
+// Its action indices are those of the Env, and actions never taken have no transitions.
+func (da *DynaAgent) Model() (*Model, error) {
+	seen := make(map[int]bool)
+	sts := make([]StochasticTransition, len(da.Q))
+	for a, counts := range da.counts {
+		if da.numVisits[a] == 0 { continue }
+		seen[da.fromID[a]] = true
+		n := float64(da.numVisits[a])
+		st := StochasticTransition{FromID: da.fromID[a], Reward: da.rewardSum[a] / n}
+		for id, c := range counts {
+			seen[id] = true
+			st.Outcomes = append(st.Outcomes, Outcome{ToID: id, Probability: float64(c) / n})
+		}
+		sort.Slice(st.Outcomes, func(i, j int) bool { return st.Outcomes[i].ToID < st.Outcomes[j].ToID })
+		sts[a] = st
+	}
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	m, err := NewStochasticModel(ids, sts)
+	if _, ok := err.(*DanglingTransitionError); ok {
+		// unobserved actions are expected to have no transitions
+		return m, nil
+	}
+	return m, err
+}
+
+func (da *DynaAgent) String() string {
+	return fmt.Sprintf("Dyna-Q agent after %d steps: %v", da.Steps, da.Q)
+}
@@ -0,0 +1,93 @@
+package mdp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDynaAgent(t *testing.T) {
+	gm := newGridModel(6, 5)
+	vi := NewValueIterator(gm)
+	vi.SetAbsorbingState(0)
+	vi.RunValueIteration()
+
+	initialStateDist := make([]float64, gm.NumStates())
+	initialStateDist[gm.StateOf[5 * 5 + 4].index] = 1
+	env, _ := NewModelEnv(gm, initialStateDist)
+	env.SetRand(rand.New(rand.NewSource(1)))
+	env.SetAbsorbingState(0)
+	da := NewDynaAgent(env, env.NumActions())
+	da.SetRand(rand.New(rand.NewSource(2)))
+	da.SetPlanningSteps(100)
+	if _, err := da.Train(30); err != nil {
+		t.Fatal(err)
+	}
+	// the greedy path from (5, 4) must be a shortest one
+	id, steps := 5 * 5 + 4, 0
+	for ; id != 0 && steps < 20; steps++ {
+		a := da.Greedy(id)
+		if a < 0 {
+			t.Fatalf("no action at %d", id)
+		}
+		id = gm.actions[a].transitions[0].state.id
+	}
+	if steps != 9 {
+		t.Errorf("greedy path length: got %d, want 9", steps)
+	}
+	// a deterministic grid has exact backups
+	a := da.Greedy(5 * 5 + 4)
+	if math.Abs(da.Q[a] - vi.Q[a]) > 1e-9 {
+		t.Errorf("Q of greedy action: got %.4f, want %.4f", da.Q[a], vi.Q[a])
+	}
+}
+
+func TestDynaAgentModel(t *testing.T) {
+	rm := newRiskModel()
+	env, _ := NewModelEnv(rm, []float64{1, 0, 0, 0})
+	env.SetRand(rand.New(rand.NewSource(1)))
+	env.SetAbsorbingState(3)
+	da := NewDynaAgent(env, env.NumActions())
+	da.SetRand(rand.New(rand.NewSource(2)))
+	da.SetEpsilon(0.5)
+	if _, err := da.Train(2000); err != nil {
+		t.Fatal(err)
+	}
+	if a := da.Greedy(0); a != 1 {
+		t.Errorf("action at 0: got %d, want 1 with %v", a, da)
+	}
+	em, err := da.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if em.NumStates() != 4 {
+		t.Errorf("states: got %d, want 4", em.NumStates())
+	}
+	sts := em.StochasticTransitions()
+	if len(sts) != 4 || sts[1].FromID != 0 || len(sts[1].Outcomes) != 2 {
+		t.Fatalf("transitions: got %v", sts)
+	}
+	if p := sts[1].Outcomes[0].Probability; math.Abs(p - 0.9) > 0.05 {
+		t.Errorf("probability of 0 -> 1: got %.3f, want about 0.9", p)
+	}
+	if sts[2].Reward != 0 || sts[3].Reward != -50 {
+		t.Errorf("rewards: got %v", sts)
+	}
+}
+
+func TestDynaAgentWithoutPlanning(t *testing.T) {
+	chain, _ := NewModel([]int{0, 1, 2}, []StateTransition{{0, 1, -1}, {1, 2, -1}})
+	env, _ := NewModelEnv(chain, []float64{1, 0, 0})
+	env.SetAbsorbingState(2)
+	da := NewDynaAgent(env, env.NumActions())
+	da.SetPlanningSteps(0)
+	for i := 0; i < 2; i++ {
+		if _, err := da.RunEpisode(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// direct updates propagate one step back per episode
+	if da.Q[0] != -2 || da.Q[1] != -1 {
+		t.Errorf("Q: got %v, want [-2 -1]", da.Q)
+	}
+}
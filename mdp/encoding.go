@@ -0,0 +1,271 @@
+package mdp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	modelMagic = "GRLM"
+	modelVersion = 1
+	maxPrealloc = 1 << 16 // upper bound of preallocation for lengths read from data
+)
+
+// ErrInvalidFormat is returned when encoded data is not a Model.
+var ErrInvalidFormat = errors.New("mdp: invalid model format")
+
+// StateIDs returns the IDs of states in the order of their indices.
+func (m *Model) StateIDs() []int {
+	ids := make([]int, len(m.states))
+	for i := range m.states {
+		ids[i] = m.states[i].id
+	}
+	return ids
+}
+
+// StochasticTransitions returns the actions of the Model in the order of their indices.
+// An action dropped on construction has no outcomes.
+func (m *Model) StochasticTransitions() []StochasticTransition {
+	sts := make([]StochasticTransition, len(m.actions))
+	for i := range m.actions {
+		a := &m.actions[i]
+		if a.state == nil { continue }
+		sts[i].FromID = a.state.id
+		sts[i].Outcomes = make([]Outcome, len(a.transitions))
+		for j, tr := range a.transitions {
+			sts[i].Outcomes[j] = Outcome{ToID: tr.state.id, Probability: tr.p}
+			sts[i].Reward = tr.r
+		}
+		if len(a.costs) > 0 {
+			sts[i].Costs = append([]float64(nil), a.costs...)
+		}
+		if len(a.rewards) > 0 {
+			sts[i].Rewards = append([]float64(nil), a.rewards...)
+		}
+	}
+	return sts
+}
+
+// newModelFromEncoding reconstructs a Model keeping the indices of the encoded one.
+// Dropped actions are encoded without outcomes and dropped again,
+// so the DanglingTransitionError is expected.
+func newModelFromEncoding(stateIDs []int, sts []StochasticTransition) (*Model, error) {
+	m, err := NewStochasticModel(stateIDs, sts)
+	if de, ok := err.(*DanglingTransitionError); ok {
+		for _, t := range de.Transitions {
+			if len(sts[t.Index].Outcomes) > 0 {
+				return nil, err
+			}
+		}
+		err = nil
+	}
+	return m, err
+}
+
+type modelJSON struct {
+	StateIDs []int `json:"states"`
+	Transitions []StochasticTransition `json:"transitions"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *Model) MarshalJSON() ([]byte, error) {
+	return json.Marshal(modelJSON{
+		StateIDs: m.StateIDs(),
+		Transitions: m.StochasticTransitions(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *Model) UnmarshalJSON(data []byte) error {
+	var mj modelJSON
+	if err := json.Unmarshal(data, &mj); err != nil {
+		return err
+	}
+	loaded, err := newModelFromEncoding(mj.StateIDs, mj.Transitions)
+	if err != nil {
+		return err
+	}
+	*m = *loaded
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (m *Model) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (m *Model) UnmarshalBinary(data []byte) error {
+	loaded, err := ReadModel(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	*m = *loaded
+	return nil
+}
+
+// WriteTo writes the Model in the binary format to w.
+// IDs and counts are encoded as varints and probabilities, rewards and costs as float64.
+func (m *Model) WriteTo(w io.Writer) (int64, error) {
+	bw := &binaryWriter{w: bufio.NewWriter(w)}
+	bw.write([]byte(modelMagic))
+	bw.writeUvarint(modelVersion)
+	bw.writeUvarint(uint64(len(m.states)))
+	for i := range m.states {
+		bw.writeVarint(int64(m.states[i].id))
+	}
+	sts := m.StochasticTransitions()
+	bw.writeUvarint(uint64(len(sts)))
+	for _, st := range sts {
+		bw.writeVarint(int64(st.FromID))
+		bw.writeFloat(st.Reward)
+		bw.writeUvarint(uint64(len(st.Costs)))
+		for _, c := range st.Costs {
+			bw.writeFloat(c)
+		}
+		bw.writeUvarint(uint64(len(st.Rewards)))
+		for _, r := range st.Rewards {
+			bw.writeFloat(r)
+		}
+		bw.writeUvarint(uint64(len(st.Outcomes)))
+		for _, o := range st.Outcomes {
+			bw.writeVarint(int64(o.ToID))
+			bw.writeFloat(o.Probability)
+		}
+	}
+	if bw.err == nil {
+		bw.err = bw.w.Flush()
+	}
+	return bw.n, bw.err
+}
+
+// ReadModel reads a Model in the binary format written by WriteTo.
+func ReadModel(r io.Reader) (*Model, error) {
+	br := &binaryReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(modelMagic))
+	br.read(magic)
+	if br.err == nil && (string(magic) != modelMagic || br.readUvarint() != modelVersion) {
+		return nil, ErrInvalidFormat
+	}
+	n := br.readCount()
+	stateIDs := make([]int, 0, minInt(n, maxPrealloc))
+	for i := 0; i < n && br.err == nil; i++ {
+		stateIDs = append(stateIDs, int(br.readVarint()))
+	}
+	n = br.readCount()
+	sts := make([]StochasticTransition, 0, minInt(n, maxPrealloc))
+	for i := 0; i < n && br.err == nil; i++ {
+		var st StochasticTransition
+		st.FromID = int(br.readVarint())
+		st.Reward = br.readFloat()
+		numCosts := br.readCount()
+		for j := 0; j < numCosts && br.err == nil; j++ {
+			st.Costs = append(st.Costs, br.readFloat())
+		}
+		numObjectives := br.readCount()
+		for j := 0; j < numObjectives && br.err == nil; j++ {
+			st.Rewards = append(st.Rewards, br.readFloat())
+		}
+		numOutcomes := br.readCount()
+		for j := 0; j < numOutcomes && br.err == nil; j++ {
+			var o Outcome
+			o.ToID = int(br.readVarint())
+			o.Probability = br.readFloat()
+			st.Outcomes = append(st.Outcomes, o)
+		}
+		sts = append(sts, st)
+	}
+	if br.err != nil {
+		if br.err == io.EOF || br.err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidFormat
+		}
+		return nil, br.err
+	}
+	return newModelFromEncoding(stateIDs, sts)
+}
+
+// binaryWriter keeps the first error and the number of written bytes.
+type binaryWriter struct {
+	w *bufio.Writer
+	n int64
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (bw *binaryWriter) write(p []byte) {
+	if bw.err != nil { return }
+	n, err := bw.w.Write(p)
+	bw.n += int64(n)
+	bw.err = err
+}
+
+func (bw *binaryWriter) writeUvarint(x uint64) {
+	bw.write(bw.buf[:binary.PutUvarint(bw.buf[:], x)])
+}
+
+func (bw *binaryWriter) writeVarint(x int64) {
+	bw.write(bw.buf[:binary.PutVarint(bw.buf[:], x)])
+}
+
+func (bw *binaryWriter) writeFloat(x float64) {
+	binary.LittleEndian.PutUint64(bw.buf[:8], math.Float64bits(x))
+	bw.write(bw.buf[:8])
+}
+
+// binaryReader keeps the first error and returns zero values after it.
+type binaryReader struct {
+	r *bufio.Reader
+	err error
+	buf [8]byte
+}
+
+func (br *binaryReader) read(p []byte) {
+	if br.err != nil { return }
+	_, br.err = io.ReadFull(br.r, p)
+}
+
+func (br *binaryReader) readUvarint() uint64 {
+	if br.err != nil { return 0 }
+	x, err := binary.ReadUvarint(br.r)
+	br.err = err
+	return x
+}
+
+func (br *binaryReader) readVarint() int64 {
+	if br.err != nil { return 0 }
+	x, err := binary.ReadVarint(br.r)
+	br.err = err
+	return x
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// readCount reads a length and guards against corrupted huge lengths.
+func (br *binaryReader) readCount() int {
+	n := br.readUvarint()
+	if n > math.MaxInt32 {
+		br.err = ErrInvalidFormat
+		return 0
+	}
+	return int(n)
+}
+
+func (br *binaryReader) readFloat() float64 {
+	br.read(br.buf[:])
+	if br.err != nil { return 0 }
+	return math.Float64frombits(binary.LittleEndian.Uint64(br.buf[:]))
+}
@@ -0,0 +1,68 @@
+package mdp
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestModelEncoding(t *testing.T) {
+	// the action from the unknown state 9 is dropped but keeps its index
+	em, _ := NewStochasticModel(
+		[]int{10, 20, 30},
+		[]StochasticTransition{
+			{FromID: 10, Outcomes: []Outcome{{20, 0.25}, {30, 0.75}}, Reward: -1.5},
+			{FromID: 9, Outcomes: []Outcome{{20, 1}}, Reward: -1},
+			{FromID: 20, Outcomes: []Outcome{{30, 1}}, Reward: -2},
+		},
+	)
+	jsonData, err := json.Marshal(em)
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	var fromJSON Model
+	if err := json.Unmarshal(jsonData, &fromJSON); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	binaryData, err := em.MarshalBinary()
+	if err != nil {
+		t.Fatalf("binary: %v", err)
+	}
+	fromBinary, err := ReadModel(bytes.NewReader(binaryData))
+	if err != nil {
+		t.Fatalf("binary: %v", err)
+	}
+
+	want := em.StochasticTransitions()
+	for name, loaded := range map[string]*Model{"json": &fromJSON, "binary": fromBinary} {
+		if loaded.NumStates() != em.NumStates() || loaded.NumActions() != em.NumActions() {
+			t.Errorf("%s: got %d states and %d actions", name, loaded.NumStates(), loaded.NumActions())
+			continue
+		}
+		got := loaded.StochasticTransitions()
+		for i := range want {
+			if got[i].Reward != want[i].Reward || len(got[i].Outcomes) != len(want[i].Outcomes) {
+				t.Errorf("%s@%d: got %v, want %v", name, i, got[i], want[i])
+				continue
+			}
+			for j := range want[i].Outcomes {
+				if got[i].Outcomes[j] != want[i].Outcomes[j] {
+					t.Errorf("%s@%d: got %v, want %v", name, i, got[i], want[i])
+				}
+			}
+		}
+		a, err := loaded.ActionByID(20, 30)
+		if err != nil || a.Index() != 2 {
+			t.Errorf("%s: got action %v, %v", name, a, err)
+		}
+	}
+
+	if _, err := ReadModel(bytes.NewReader(binaryData[:len(binaryData)-3])); err != ErrInvalidFormat {
+		t.Errorf("truncated: got %v, want %v", err, ErrInvalidFormat)
+	}
+	other := append([]byte(nil), binaryData...)
+	other[len(modelMagic)] = modelVersion + 1
+	if _, err := ReadModel(bytes.NewReader(other)); err != ErrInvalidFormat {
+		t.Errorf("other version: got %v, want %v", err, ErrInvalidFormat)
+	}
+}
@@ -0,0 +1,125 @@
+package mdp
+
+import (
+	"math/rand"
+)
+
+// Env describes the requirements of an environment for model-free learning.
+// Actions are identified by indices, e.g. those of a Model,
+// so that learned Q tables are comparable with ValueIterator.Q.
+type Env interface {
+	// Reset starts a new episode and returns the initial state ID.
+	Reset() int
+	// Actions returns the indices of the actions available at a state,
+	// which are none at terminal states.
+	Actions(stateID int) []int
+	// Step takes an action at the current state
+	// and returns the next state ID, the reward and whether the episode is done.
+	// An episode may be done at a non-terminal state, e.g. by a limit of steps.
+	Step(actionIndex int) (int, float64, bool, error)
+}
+
+// ModelEnv is an Env which simulates a Model.
+// An episode starts from a state drawn from the initial state distribution
+// and is done on an absorbing state, a state without actions, or after the maximum steps.
+type ModelEnv struct {
+	model *Model
+	initialStateDist []float64
+	absorbing
+	maxSteps int // unlimited if 0
+	state *State
+	steps int
+	rand *rand.Rand
+}
+
+// NewModelEnv constructs a ModelEnv instance from a given Model and initial state distribution.
+// The distribution is given by non-negative weights, which are normalized.
+// It returns *InvalidDistributionError if a weight is negative or none is positive.
+func NewModelEnv(model *Model, initialStateDist []float64) (*ModelEnv, error) {
+	if len(initialStateDist) != len(model.states) {
+		return nil, &DimensionMismatchError{Got: len(initialStateDist), Want: len(model.states)}
+	}
+	z := 0.0
+	for i, p := range initialStateDist {
+		if !(p >= 0) {
+			return nil, &InvalidDistributionError{i}
+		}
+		z += p
+	}
+	if z <= 0 {
+		return nil, &InvalidDistributionError{-1}
+	}
+	dist := make([]float64, len(initialStateDist))
+	for i, p := range initialStateDist {
+		dist[i] = p / z
+	}
+	return &ModelEnv{
+		model: model,
+		initialStateDist: dist,
+		absorbing: newAbsorbing(model, len(model.states), nil),
+		rand: rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+// SetRand sets the source of randomness of initial states and transitions,
+// e.g. seeded for reproducibility.
+func (env *ModelEnv) SetRand(r *rand.Rand) {
+	env.rand = r
+}
+
+// SetMaxSteps sets the maximum number of steps of an episode. It is unlimited if 0.
+func (env *ModelEnv) SetMaxSteps(maxSteps int) {
+	env.maxSteps = maxSteps
+}
+
+// NumActions returns a number of actions of the Model.
+func (env *ModelEnv) NumActions() int {
+	return len(env.model.actions)
+}
+
+// Reset implements Env.
+func (env *ModelEnv) Reset() int {
+	r := env.rand.Float64()
+	cumP := 0.0
+	idx := len(env.initialStateDist) - 1
+	for i, p := range env.initialStateDist {
+		cumP += p
+		if r < cumP {
+			idx = i
+			break
+		}
+	}
+	env.state = &env.model.states[idx]
+	env.steps = 0
+	return env.state.id
+}
+
+// Actions implements Env.
+func (env *ModelEnv) Actions(stateID int) []int {
+	s, ok := env.model.StateOf[stateID]
+	if !ok || env.isAbsorbing[s.index] {
+		return nil
+	}
+	actions := make([]int, len(s.actions))
+	for i, a := range s.actions {
+		actions[i] = a.index
+	}
+	return actions
+}
+
+// Step implements Env.
+// It returns *UnknownActionError if the action is not available at the current state.
+func (env *ModelEnv) Step(actionIndex int) (int, float64, bool, error) {
+	if env.state == nil {
+		env.Reset()
+	}
+	if actionIndex < 0 || actionIndex >= len(env.model.actions) || env.model.actions[actionIndex].state != env.state {
+		return env.state.id, 0, false, &UnknownActionError{actionIndex}
+	}
+	tr := transitionAt(&env.model.actions[actionIndex], env.rand.Float64())
+	env.state = tr.state
+	env.steps++
+	done := env.isAbsorbing[tr.state.index] || len(tr.state.actions) == 0 ||
+		(env.maxSteps > 0 && env.steps >= env.maxSteps)
+	return tr.state.id, tr.r, done, nil
+}
@@ -0,0 +1,153 @@
+package mdp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoAction is returned when an operation requires a non-empty action set.
+var ErrNoAction = errors.New("mdp: empty action set")
+
+// ErrNotConverged is returned when an iteration limit is reached before convergence.
+var ErrNotConverged = errors.New("mdp: not converged within the iteration limit")
+
+// UnknownStateError reports a state ID which is not in the Model.
+type UnknownStateError struct {
+	ID int
+}
+
+func (e *UnknownStateError) Error() string {
+	return fmt.Sprintf("mdp: unknown state ID %d", e.ID)
+}
+
+// UnknownActionError reports an action index which is not in the Model.
+type UnknownActionError struct {
+	Index int
+}
+
+func (e *UnknownActionError) Error() string {
+	return fmt.Sprintf("mdp: unknown action index %d", e.Index)
+}
+
+// UnknownCostError reports an auxiliary cost index which is not in the Model.
+type UnknownCostError struct {
+	Index int
+}
+
+func (e *UnknownCostError) Error() string {
+	return fmt.Sprintf("mdp: unknown cost index %d", e.Index)
+}
+
+// UnknownObjectiveError reports an objective index of vector rewards which is not in the Model.
+type UnknownObjectiveError struct {
+	Index int
+}
+
+func (e *UnknownObjectiveError) Error() string {
+	return fmt.Sprintf("mdp: unknown objective index %d", e.Index)
+}
+
+// DimensionMismatchError reports a slice whose length does not match the Model.
+type DimensionMismatchError struct {
+	Got, Want int
+}
+
+func (e *DimensionMismatchError) Error() string {
+	return fmt.Sprintf("mdp: dimension mismatch: got %d, want %d", e.Got, e.Want)
+}
+
+// InvalidOptionError reports a convergence setting out of its range.
+type InvalidOptionError struct {
+	Name string
+	Value float64
+}
+
+func (e *InvalidOptionError) Error() string {
+	return fmt.Sprintf("mdp: invalid option %s: %v", e.Name, e.Value)
+}
+
+// DanglingTransition is a transition dropped from a Model.
+// UnknownIDs holds the state IDs of the transition which are not in the Model.
+// It is empty if the transition has no outcome.
+type DanglingTransition struct {
+	Index int // index of the transition given to the constructor
+	FromID int
+	UnknownIDs []int
+}
+
+// DanglingTransitionError reports every transition dropped from a Model.
+type DanglingTransitionError struct {
+	Transitions []DanglingTransition
+}
+
+func (e *DanglingTransitionError) Error() string {
+	t := e.Transitions[0]
+	if len(t.UnknownIDs) == 0 {
+		return fmt.Sprintf("mdp: %d dangling transitions dropped (#%d from %d has no outcome)",
+			len(e.Transitions), t.Index, t.FromID)
+	}
+	return fmt.Sprintf("mdp: %d dangling transitions dropped (#%d from %d references unknown state IDs %v)",
+		len(e.Transitions), t.Index, t.FromID, t.UnknownIDs)
+}
+
+// NoTransitionError reports a pair of states which no action connects.
+type NoTransitionError struct {
+	FromID, ToID int
+}
+
+func (e *NoTransitionError) Error() string {
+	return fmt.Sprintf("mdp: no transition from %d to %d", e.FromID, e.ToID)
+}
+
+// DeadEndError reports a non-goal state without actions.
+type DeadEndError struct {
+	ID int
+}
+
+func (e *DeadEndError) Error() string {
+	return fmt.Sprintf("mdp: dead end at state ID %d", e.ID)
+}
+
+func (e *DeadEndError) Unwrap() error {
+	return ErrNoAction
+}
+
+// InvalidPolicyError reports a state where a policy gives no probability to any available action.
+type InvalidPolicyError struct {
+	StateID int
+}
+
+func (e *InvalidPolicyError) Error() string {
+	return fmt.Sprintf("mdp: policy has no probability over the actions of state ID %d", e.StateID)
+}
+
+// InvalidDistributionError reports weights which do not define a probability distribution.
+type InvalidDistributionError struct {
+	Index int // index of a negative weight, or -1 if no weight is positive
+}
+
+func (e *InvalidDistributionError) Error() string {
+	if e.Index < 0 {
+		return "mdp: distribution has no positive weight"
+	}
+	return fmt.Sprintf("mdp: distribution has an invalid weight at index %d", e.Index)
+}
+
+// UnreachableGoalError reports a goal which was not reached from a start state.
+type UnreachableGoalError struct {
+	StartID, GoalID int
+	Steps int
+}
+
+func (e *UnreachableGoalError) Error() string {
+	return fmt.Sprintf("mdp: goal %d not reached from %d in %d steps", e.GoalID, e.StartID, e.Steps)
+}
+
+// InvalidIntervalError reports bounds of an Interval which contain no probability distribution.
+type InvalidIntervalError struct {
+	Lower, Upper []float64
+}
+
+func (e *InvalidIntervalError) Error() string {
+	return fmt.Sprintf("mdp: interval from %v to %v contains no probability distribution", e.Lower, e.Upper)
+}
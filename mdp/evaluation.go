@@ -0,0 +1,251 @@
+package mdp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Experience is a step observed in an episode.
+type Experience struct {
+	StateID int
+	ActionIndex int
+	Reward float64
+	NextStateID int
+}
+
+// Episode is a sequence of experiences where each NextStateID is the StateID of the next one.
+type Episode []Experience
+
+// SampleEpisode runs an episode on env following a stochastic policy indexed by action index,
+// drawing actions from rnd. It stops when env is done, at a state without actions, or after maxSteps.
+// It returns *DimensionMismatchError if the policy does not cover an action of env,
+// and *InvalidPolicyError if the policy gives no probability to the actions of a state.
+func SampleEpisode(env Env, policy []float64, maxSteps int, rnd *rand.Rand) (Episode, error) {
+	stateID := env.Reset()
+	episode := make(Episode, 0)
+	for k := 0; k < maxSteps; k++ {
+		actions := env.Actions(stateID)
+		if len(actions) == 0 {
+			break
+		}
+		a := actions[len(actions) - 1]
+		z := 0.0
+		for _, i := range actions {
+			if i < 0 || i >= len(policy) {
+				return episode, &DimensionMismatchError{Got: len(policy), Want: i + 1}
+			}
+			z += policy[i]
+		}
+		if z <= 0 {
+			return episode, &InvalidPolicyError{stateID}
+		}
+		r := rnd.Float64() * z
+		cumP := 0.0
+		for _, i := range actions {
+			cumP += policy[i]
+			if r < cumP {
+				a = i
+				break
+			}
+		}
+		nextID, reward, done, err := env.Step(a)
+		if err != nil {
+			return episode, err
+		}
+		episode = append(episode, Experience{stateID, a, reward, nextID})
+		stateID = nextID
+		if done {
+			break
+		}
+	}
+	return episode, nil
+}
+
+// PolicyEvaluator evaluates state values of a fixed policy,
+// exactly from a Model or from sampled episodes.
+type PolicyEvaluator struct {
+	model *Model
+	V []float64 // state values
+	Visits []int // number of updates of each state value
+	absorbing
+	gamma float64 // discount factor
+	convergence
+}
+
+// NewPolicyEvaluator constructs a PolicyEvaluator instance from a given Model.
+func NewPolicyEvaluator(model *Model) *PolicyEvaluator {
+	return NewPolicyEvaluatorWithOptions(model, DefaultOptions())
+}
+
+// NewPolicyEvaluatorWithOptions constructs a PolicyEvaluator instance
+// from a given Model and convergence settings.
+func NewPolicyEvaluatorWithOptions(model *Model, opts Options) *PolicyEvaluator {
+	V := make([]float64, len(model.states))
+	return &PolicyEvaluator{
+		model: model,
+		V: V,
+		Visits: make([]int, len(model.states)),
+		absorbing: newAbsorbing(model, len(model.states), V),
+		gamma: 1,
+		convergence: convergence{opts},
+	}
+}
+
+// SetGamma sets a discount factor.
+func (pe *PolicyEvaluator) SetGamma(gamma float64) {
+	pe.gamma = gamma
+}
+
+// Init initializes V and Visits.
+func (pe *PolicyEvaluator) Init() {
+	for i := range pe.V {
+		pe.V[i] = 0
+		pe.Visits[i] = 0
+	}
+}
+
+// Evaluate computes V of a policy indexed by action index by iterative policy evaluation.
+// It returns *DimensionMismatchError if the policy does not cover every action.
+func (pe *PolicyEvaluator) Evaluate(policy []float64) (ConvergenceReport, error) {
+	return pe.EvaluateContext(context.Background(), policy)
+}
+
+// EvaluateContext runs Evaluate until convergence or cancellation of ctx.
+// ctx is checked at each sweep.
+func (pe *PolicyEvaluator) EvaluateContext(ctx context.Context, policy []float64) (ConvergenceReport, error) {
+	m := pe.model
+	if len(policy) != len(m.actions) {
+		return ConvergenceReport{}, &DimensionMismatchError{Got: len(policy), Want: len(m.actions)}
+	}
+	report := ConvergenceReport{Iterations: make([]int, 1)}
+	var k int
+	for k = 0; k < pe.opts.MaxIterations; k++ {
+		if ctx.Err() != nil {
+			report.Iterations[0] = k
+			return report, ctx.Err()
+		}
+		report.Residual = 0
+		for stateIdx := range m.states {
+			if pe.isAbsorbing[stateIdx] { continue }
+			v := 0.0
+			for _, a := range m.states[stateIdx].actions {
+				if policy[a.index] == 0 { continue }
+				q := 0.0
+				for _, tr := range a.transitions {
+					q += tr.p * (tr.r + pe.gamma * pe.V[tr.state.index])
+				}
+				v += policy[a.index] * q
+			}
+			report.Residual = math.Max(report.Residual, math.Abs(v - pe.V[stateIdx]))
+			pe.V[stateIdx] = v
+		}
+		if report.Residual < pe.opts.MinTDError {
+			k++
+			break
+		}
+	}
+	report.Iterations[0] = k
+	report.HitMaxIterations = report.Residual >= pe.opts.MinTDError
+	return report, nil
+}
+
+// indices returns the state index of each experience and of the state after the last one.
+func (pe *PolicyEvaluator) indices(episode Episode) ([]int, error) {
+	idx := make([]int, len(episode) + 1)
+	for i, e := range episode {
+		s, ok := pe.model.StateOf[e.StateID]
+		if !ok { return nil, &UnknownStateError{e.StateID} }
+		idx[i] = s.index
+	}
+	if len(episode) > 0 {
+		last := episode[len(episode) - 1].NextStateID
+		s, ok := pe.model.StateOf[last]
+		if !ok { return nil, &UnknownStateError{last} }
+		idx[len(episode)] = s.index
+	}
+	return idx, nil
+}
+
+// MonteCarlo updates V to the average of the discounted returns observed in episodes,
+// together with those of previous calls since Init.
+// With firstVisit, only the first visit of a state in each episode counts.
+// Episodes which do not end at an absorbing state or a state without actions,
+// e.g. truncated by a limit of steps, are skipped since their returns are incomplete.
+// It returns the number of episodes used.
+func (pe *PolicyEvaluator) MonteCarlo(episodes []Episode, firstVisit bool) (int, error) {
+	used := 0
+	for _, episode := range episodes {
+		idx, err := pe.indices(episode)
+		if err != nil {
+			return used, err
+		}
+		if len(episode) == 0 || !pe.isTerminal(idx[len(episode)]) { continue }
+		used++
+		first := make(map[int]int)
+		for i := len(episode) - 1; i >= 0; i-- {
+			first[idx[i]] = i
+		}
+		g := 0.0
+		for i := len(episode) - 1; i >= 0; i-- {
+			g = episode[i].Reward + pe.gamma * g
+			s := idx[i]
+			if firstVisit && first[s] != i { continue }
+			pe.Visits[s]++
+			pe.V[s] += (g - pe.V[s]) / float64(pe.Visits[s])
+		}
+	}
+	return used, nil
+}
+
+// isTerminal reports whether a state index ends episodes.
+func (pe *PolicyEvaluator) isTerminal(s int) bool {
+	return pe.isAbsorbing[s] || len(pe.model.states[s].actions) == 0
+}
+
+// TDLambda updates V online by TD(lambda) with accumulating eligibility traces.
+// An episode bootstraps from V of its last state unless it is absorbing or has no actions.
+// Each step updates only the states with non-zero traces.
+func (pe *PolicyEvaluator) TDLambda(episodes []Episode, lambda, learningRate float64) error {
+	trace := make([]float64, len(pe.V))
+	traced := make([]int, 0) // state indices with non-zero traces
+	for _, episode := range episodes {
+		idx, err := pe.indices(episode)
+		if err != nil {
+			return err
+		}
+		for _, j := range traced {
+			trace[j] = 0
+		}
+		traced = traced[:0]
+		for i, e := range episode {
+			s, next := idx[i], idx[i + 1]
+			v := 0.0
+			if !pe.isTerminal(next) {
+				v = pe.V[next]
+			}
+			delta := e.Reward + pe.gamma * v - pe.V[s]
+			if trace[s] == 0 {
+				traced = append(traced, s)
+			}
+			trace[s]++
+			pe.Visits[s]++
+			n := 0
+			for _, j := range traced {
+				pe.V[j] += learningRate * delta * trace[j]
+				trace[j] *= pe.gamma * lambda
+				if trace[j] != 0 {
+					traced[n] = j
+					n++
+				}
+			}
+			traced = traced[:n]
+		}
+	}
+	return nil
+}
+
+func (pe *PolicyEvaluator) String() string {
+	return fmt.Sprintf("V: %v\nVisits: %v", pe.V, pe.Visits)
+}
@@ -0,0 +1,103 @@
+package mdp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestPolicyEvaluation(t *testing.T) {
+	gm := newGridModel(6, 5)
+	vi := NewValueIterator(gm)
+	vi.SetAbsorbingState(0)
+	vi.RunValueIteration()
+	vi.SetAlpha(0)
+	vi.UpdatePolicy()
+
+	pe := NewPolicyEvaluator(gm)
+	pe.SetAbsorbingState(0)
+	if report, err := pe.Evaluate(vi.Policy); err != nil || report.HitMaxIterations {
+		t.Fatalf("got %v, %v", report, err)
+	}
+	for i := range pe.V {
+		if math.Abs(pe.V[i] - vi.V[i]) > 1e-3 {
+			t.Errorf("V@%d: got %.4f, want %.4f", i, pe.V[i], vi.V[i])
+		}
+	}
+	if _, err := pe.Evaluate(nil); err == nil {
+		t.Errorf("got nil, want *DimensionMismatchError")
+	}
+}
+
+func TestSampledPolicyEvaluation(t *testing.T) {
+	rm := newRiskModel()
+	// taking each action at 0 with probability 0.5 is worth (-10 - 6) / 2
+	policy := []float64{0.5, 0.5, 1, 1}
+	pe := NewPolicyEvaluator(rm)
+	pe.SetAbsorbingState(3)
+	if _, err := pe.Evaluate(policy); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(pe.V[0] + 8) > 1e-9 {
+		t.Errorf("exact V@0: got %.4f, want -8", pe.V[0])
+	}
+
+	env, _ := NewModelEnv(rm, []float64{1, 0, 0, 0})
+	env.SetRand(rand.New(rand.NewSource(1)))
+	env.SetAbsorbingState(3)
+	rnd := rand.New(rand.NewSource(2))
+	episodes := make([]Episode, 40000)
+	for i := range episodes {
+		var err error
+		if episodes[i], err = SampleEpisode(env, policy, 10, rnd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, firstVisit := range []bool{true, false} {
+		pe.Init()
+		if n, err := pe.MonteCarlo(episodes, firstVisit); err != nil || n != len(episodes) {
+			t.Fatalf("got %d episodes used, %v", n, err)
+		}
+		if pe.Visits[0] != len(episodes) || math.Abs(pe.V[0] + 8) > 1 {
+			t.Errorf("Monte Carlo (first visit=%v): got %v", firstVisit, pe)
+		}
+	}
+	for _, lambda := range []float64{0, 0.5, 1} {
+		pe.Init()
+		if err := pe.TDLambda(episodes, lambda, 0.005); err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(pe.V[0] + 8) > 2 || math.Abs(pe.V[2] + 50) > 0.1 {
+			t.Errorf("TD(%v): got %v", lambda, pe)
+		}
+	}
+
+	// every-visit counts revisits which first-visit skips
+	loop, _ := NewModel([]int{0, 1}, []StateTransition{{0, 0, -1}, {0, 1, -1}})
+	pe = NewPolicyEvaluator(loop)
+	pe.SetAbsorbingState(1)
+	episode := Episode{{0, 0, -1, 0}, {0, 0, -1, 0}, {0, 1, -1, 1}}
+	if n, _ := pe.MonteCarlo([]Episode{episode, episode[:2]}, true); n != 1 {
+		t.Errorf("episodes used: got %d, want 1 without the truncated one", n)
+	}
+	if pe.Visits[0] != 1 || pe.V[0] != -3 {
+		t.Errorf("first visit: got %v", pe)
+	}
+	pe.Init()
+	pe.MonteCarlo([]Episode{episode}, false)
+	if pe.Visits[0] != 3 || pe.V[0] != -2 {
+		t.Errorf("every visit: got %v", pe)
+	}
+	if _, err := pe.MonteCarlo([]Episode{{{5, 0, 0, 0}}}, true); err == nil {
+		t.Errorf("got nil, want *UnknownStateError")
+	}
+
+	loopEnv, _ := NewModelEnv(loop, []float64{1, 0})
+	loopEnv.SetAbsorbingState(1)
+	if _, err := SampleEpisode(loopEnv, []float64{1}, 10, rnd); err == nil {
+		t.Errorf("got nil, want *DimensionMismatchError")
+	}
+	if _, err := SampleEpisode(loopEnv, []float64{0, 0}, 10, rnd); err == nil {
+		t.Errorf("got nil, want *InvalidPolicyError")
+	}
+}
@@ -0,0 +1,256 @@
+package mdp
+
+import (
+	"context"
+	"fmt"
+	"github.com/misteroda/go-rl/base"
+)
+
+// TimeRewardFunc returns the reward of an action taken at time t.
+// It replaces the rewards of the Model, e.g. to model time-of-day dependent costs.
+type TimeRewardFunc func(t, actionIndex int) float64
+
+// FiniteHorizonSolver represents backward induction over a finite horizon.
+// V, Q and Policy are indexed by time first, so the plan at time t may differ
+// from the plan at another time with the same state, e.g. near a deadline.
+type FiniteHorizonSolver struct {
+	model *Model
+	horizon int
+	V [][]float64 // state values for t = 0, ..., horizon
+	Q [][]float64 // state-action values for t = 0, ..., horizon - 1
+	Policy [][]float64
+	actionSpace
+	terminal []float64 // state values at the horizon
+	alpha float64 // temperature parameter for softmax operator
+	gamma float64 // discount factor
+	reward TimeRewardFunc
+}
+
+// NewFiniteHorizonSolver constructs a FiniteHorizonSolver instance
+// planning horizon steps ahead from a given Model.
+func NewFiniteHorizonSolver(model *Model, horizon int) *FiniteHorizonSolver {
+	if horizon < 0 {
+		horizon = 0
+	}
+	fh := FiniteHorizonSolver{
+		model: model,
+		horizon: horizon,
+		V: make([][]float64, horizon + 1),
+		Q: make([][]float64, horizon),
+		Policy: make([][]float64, horizon),
+		actionSpace: newActionSpace(model, nil),
+		terminal: make([]float64, len(model.states)),
+		gamma: 1.0,
+	}
+	for t := range fh.V {
+		fh.V[t] = make([]float64, len(model.states))
+	}
+	for t := range fh.Q {
+		fh.Q[t] = make([]float64, len(model.actions))
+		fh.Policy[t] = make([]float64, len(model.actions))
+	}
+	return &fh
+}
+
+// Horizon returns the number of steps to plan.
+func (fh *FiniteHorizonSolver) Horizon() int {
+	return fh.horizon
+}
+
+// SetAlpha sets a value of alpha.
+func (fh *FiniteHorizonSolver) SetAlpha(alpha float64) {
+	fh.alpha = alpha
+}
+
+// SetGamma sets a discount factor.
+func (fh *FiniteHorizonSolver) SetGamma(gamma float64) {
+	fh.gamma = gamma
+}
+
+// SetTerminalValue sets state values at the horizon, which are 0 by default.
+// A large negative value on every non-goal state models a deadline.
+func (fh *FiniteHorizonSolver) SetTerminalValue(V []float64) error {
+	if len(V) != len(fh.terminal) {
+		return &DimensionMismatchError{Got: len(V), Want: len(fh.terminal)}
+	}
+	copy(fh.terminal, V)
+	return nil
+}
+
+// SetRewardFunc sets time-dependent rewards. With nil, the rewards of the Model are used.
+func (fh *FiniteHorizonSolver) SetRewardFunc(reward TimeRewardFunc) {
+	fh.reward = reward
+}
+
+// Init initializes FiniteHorizonSolver instance.
+func (fh *FiniteHorizonSolver) Init() {
+	for t := range fh.V {
+		base.Vector(fh.V[t]).Fill(0.0)
+	}
+	for t := range fh.Q {
+		base.Vector(fh.Q[t]).Fill(0.0)
+		base.Vector(fh.Policy[t]).Fill(0.0)
+	}
+	base.Vector(fh.terminal).Fill(0.0)
+	fh.InitAbsorbingState()
+	fh.alpha = 0.0
+	fh.gamma = 1.0
+	fh.reward = nil
+}
+
+// at returns a ValueIterator sharing Q and Policy at time t,
+// so that softmax and action selection are the same as in Value Iteration.
+func (fh *FiniteHorizonSolver) at(t int) *ValueIterator {
+	return &ValueIterator{
+		model: fh.model,
+		Q: fh.Q[t],
+		Policy: fh.Policy[t],
+		actionSpace: fh.actionSpace,
+		alpha: fh.alpha,
+	}
+}
+
+// RunBackwardInduction computes V and Q from the horizon back to time 0.
+func (fh *FiniteHorizonSolver) RunBackwardInduction() {
+	fh.RunBackwardInductionContext(context.Background())
+}
+
+// RunBackwardInductionContext runs RunBackwardInduction until completion or cancellation of ctx.
+// ctx is checked at each time step.
+func (fh *FiniteHorizonSolver) RunBackwardInductionContext(ctx context.Context) error {
+	m := fh.model
+	copy(fh.V[fh.horizon], fh.terminal)
+	for stateIdx, absorbing := range fh.isAbsorbing {
+		if absorbing {
+			fh.V[fh.horizon][stateIdx] = 0
+		}
+	}
+	for t := fh.horizon - 1; t >= 0; t-- {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		vi := fh.at(t)
+		next := fh.V[t + 1]
+		for stateIdx := range m.states {
+			s := &m.states[stateIdx]
+			actions := fh.ToActions(s)
+			for _, a := range actions {
+				q := 0.0
+				for _, tr := range a.transitions {
+					r := tr.r
+					if fh.reward != nil {
+						r = fh.reward(t, a.index)
+					}
+					q += tr.p * (r + fh.gamma * next[tr.state.index])
+				}
+				fh.Q[t][a.index] = q
+			}
+			v, err := vi.softMax(actions)
+			if err != nil {
+				// absorbing states and dead ends keep their value
+				v = next[stateIdx]
+				if fh.isAbsorbing[stateIdx] {
+					v = 0
+				}
+			}
+			fh.V[t][stateIdx] = v
+		}
+	}
+	return nil
+}
+
+// Solve runs backward induction until completion or cancellation of ctx.
+func (fh *FiniteHorizonSolver) Solve(ctx context.Context) error {
+	return fh.RunBackwardInductionContext(ctx)
+}
+
+// UpdatePolicy updates the policy at each time based on current state-action values.
+func (fh *FiniteHorizonSolver) UpdatePolicy() {
+	for t := range fh.Policy {
+		fh.at(t).UpdatePolicy()
+	}
+}
+
+// TimeExpandedVisitation computes state-action visitation frequency distribution at each time
+// based on the current policy. stateDist has horizon + 1 rows and actionDist has horizon rows.
+// Visitation stops at absorbing states.
+func (fh *FiniteHorizonSolver) TimeExpandedVisitation(initialStateDist []float64) ([][]float64, [][]float64) {
+	m := fh.model
+	stateDist := make([][]float64, fh.horizon + 1)
+	actionDist := make([][]float64, fh.horizon)
+	stateDist[0] = make([]float64, len(m.states))
+	copy(stateDist[0], initialStateDist)
+	for t := 0; t < fh.horizon; t++ {
+		stateDist[t + 1] = make([]float64, len(m.states))
+		actionDist[t] = make([]float64, len(m.actions))
+		for stateIdx, sd := range stateDist[t] {
+			if sd == 0 { continue }
+			s := &m.states[stateIdx]
+			for _, a := range fh.ToActions(s) {
+				d := sd * fh.Policy[t][a.index]
+				actionDist[t][a.index] = d
+				for _, tr := range a.transitions {
+					stateDist[t + 1][tr.state.index] += fh.gamma * d * tr.p
+				}
+			}
+		}
+	}
+	return stateDist, actionDist
+}
+
+// StateActionVisitationContext computes the visitation frequency distribution summed over time.
+// It returns an error only if ctx is done.
+func (fh *FiniteHorizonSolver) StateActionVisitationContext(ctx context.Context, initialStateDist []float64) ([]float64, []float64, error) {
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+	stateDist, actionDist := fh.TimeExpandedVisitation(initialStateDist)
+	sumState := make([]float64, len(fh.model.states))
+	for _, sd := range stateDist {
+		base.Vector(sumState).Add(base.Vector(sd))
+	}
+	sumAction := make([]float64, len(fh.model.actions))
+	for _, ad := range actionDist {
+		base.Vector(sumAction).Add(base.Vector(ad))
+	}
+	return sumState, sumAction, nil
+}
+
+// GenerateTrajectory generates trajectory of given a start and a goal state
+// following the policy at each time up to the horizon.
+// If the goal is not reached, the trajectory so far is returned with
+// *DeadEndError or *UnreachableGoalError.
+func (fh *FiniteHorizonSolver) GenerateTrajectory(startID, goalID int) ([]int, error) {
+	m := fh.model
+	startState, ok := m.StateOf[startID]
+	if !ok { return nil, &UnknownStateError{startID} }
+	goalState, ok := m.StateOf[goalID]
+	if !ok { return nil, &UnknownStateError{goalID} }
+	s := startState
+	tr := []int{s.id}
+	if s == goalState {
+		return tr, nil
+	}
+	for t := 0; t < fh.horizon; t++ {
+		a, err := fh.at(t).sampleAction(fh.ToActions(s))
+		if err != nil {
+			return tr, &DeadEndError{s.id}
+		}
+		s = sampleTransition(a).state
+		tr = append(tr, s.id)
+		if s == goalState {
+			return tr, nil
+		}
+	}
+	return tr, &UnreachableGoalError{StartID: startID, GoalID: goalID, Steps: fh.horizon}
+}
+
+func (fh *FiniteHorizonSolver) String() string {
+	var s string
+	for t := range fh.Q {
+		s += fmt.Sprintf("t=%d V: %v\n", t, fh.V[t])
+		s += fmt.Sprintf("t=%d Policy: %v\n", t, fh.Policy[t])
+	}
+	s += fmt.Sprintf("t=%d V: %v\n", fh.horizon, fh.V[fh.horizon])
+	return s
+}
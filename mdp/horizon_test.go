@@ -0,0 +1,82 @@
+package mdp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFiniteHorizon(t *testing.T) {
+	gm := newGridModel(6, 5)
+	startID, goalID := 5 * 5 + 4, 0
+	penalty := make([]float64, gm.NumStates())
+	for i := range penalty {
+		penalty[i] = -100
+	}
+	for _, tc := range []struct {
+		horizon int
+		wantV float64
+		reached bool
+	}{
+		{9, -9, true},
+		{12, -9, true},
+		{8, -108, false},
+	} {
+		fh := NewFiniteHorizonSolver(gm, tc.horizon)
+		fh.SetAbsorbingState(goalID)
+		if err := fh.SetTerminalValue(penalty); err != nil {
+			t.Fatal(err)
+		}
+		fh.RunBackwardInduction()
+		fh.UpdatePolicy()
+		start := gm.StateOf[startID].index
+		if math.Abs(fh.V[0][start] - tc.wantV) > 1e-9 {
+			t.Errorf("horizon=%d V[0]: got %.3f, want %.3f", tc.horizon, fh.V[0][start], tc.wantV)
+		}
+		tr, err := fh.GenerateTrajectory(startID, goalID)
+		if tc.reached && (err != nil || len(tr) != 10) {
+			t.Errorf("horizon=%d trajectory: got %v, %v", tc.horizon, tr, err)
+		}
+		if !tc.reached {
+			if _, ok := err.(*UnreachableGoalError); !ok {
+				t.Errorf("horizon=%d error: got %v, want *UnreachableGoalError", tc.horizon, err)
+			}
+		}
+
+		initialStateDist := make([]float64, gm.NumStates())
+		initialStateDist[start] = 1
+		stateDist, actionDist := fh.TimeExpandedVisitation(initialStateDist)
+		if len(stateDist) != tc.horizon + 1 || len(actionDist) != tc.horizon {
+			t.Fatalf("horizon=%d visitation: got %d and %d rows", tc.horizon, len(stateDist), len(actionDist))
+		}
+		goal := gm.StateOf[goalID].index
+		if tc.reached && math.Abs(stateDist[9][goal] - 1) > 1e-9 {
+			t.Errorf("horizon=%d goal visitation at t=9: got %.3f, want 1", tc.horizon, stateDist[9][goal])
+		}
+	}
+}
+
+func TestTimeDependentReward(t *testing.T) {
+	// waiting at 0 costs 1, and moving to 1 costs 10 at t = 0 and 1 later.
+	tm, _ := NewModel([]int{0, 1}, []StateTransition{
+		{0, 0, -1},
+		{0, 1, -1},
+	})
+	wait, _ := tm.ActionByID(0, 0)
+	move, _ := tm.ActionByID(0, 1)
+	fh := NewFiniteHorizonSolver(tm, 3)
+	fh.SetAbsorbingState(1)
+	fh.SetRewardFunc(func(t, actionIndex int) float64 {
+		if actionIndex == move.Index() && t == 0 {
+			return -10
+		}
+		return -1
+	})
+	fh.RunBackwardInduction()
+	fh.UpdatePolicy()
+	if fh.Policy[0][wait.Index()] != 1 || fh.Policy[1][move.Index()] != 1 {
+		t.Errorf("policy: got %v", fh.Policy)
+	}
+	if fh.V[0][0] != -2 {
+		t.Errorf("V[0]: got %.3f, want -2", fh.V[0][0])
+	}
+}
@@ -0,0 +1,272 @@
+package mdp
+
+import (
+	"fmt"
+	"github.com/misteroda/go-rl/base"
+)
+
+// OccupancyConstraint is a linear constraint on state-action occupancy,
+// i.e. sum of Coef[a] * Occupancy[a] (Sense) Bound, with Coef indexed by action index.
+type OccupancyConstraint struct {
+	Coef []float64
+	Sense ConstraintSense
+	Bound float64
+}
+
+// LPSolver solves a Model exactly by linear programming.
+// The primal program is over state values, and the dual program is over
+// state-action occupancy measures, which may be restricted by OccupancyConstraints.
+// States without actions terminate an episode as absorbing states do.
+type LPSolver struct {
+	model *Model
+	V []float64 // state values
+	Q []float64 // state-action values
+	Occupancy []float64 // state-action occupancy measure
+	Policy []float64
+	Multipliers []float64 // shadow prices of occupancy constraints
+	greedyPolicy
+	gamma float64 // discount factor
+	constraints []OccupancyConstraint
+}
+
+// NewLPSolver constructs a LPSolver instance from a given Model.
+func NewLPSolver(model *Model) *LPSolver {
+	V := make([]float64, len(model.states))
+	Q := make([]float64, len(model.actions))
+	policy := make([]float64, len(model.actions))
+	lps := LPSolver{
+		model: model,
+		V: V,
+		Q: Q,
+		Occupancy: make([]float64, len(model.actions)),
+		Policy: policy,
+		greedyPolicy: newGreedyPolicy(model, V, Q, policy),
+		gamma: 1.0,
+	}
+	return &lps
+}
+
+// SetGamma sets a discount factor.
+// With gamma = 1, every state is assumed to reach an absorbing state.
+func (lps *LPSolver) SetGamma(gamma float64) {
+	lps.gamma = gamma
+}
+
+// AddOccupancyConstraint adds a constraint on occupancy to the dual program.
+func (lps *LPSolver) AddOccupancyConstraint(c OccupancyConstraint) error {
+	if len(c.Coef) != len(lps.model.actions) {
+		return &DimensionMismatchError{Got: len(c.Coef), Want: len(lps.model.actions)}
+	}
+	lps.constraints = append(lps.constraints, c)
+	return nil
+}
+
+// Init initializes LPSolver instance and removes occupancy constraints.
+func (lps *LPSolver) Init() {
+	base.Vector(lps.V).Fill(0.0)
+	base.Vector(lps.Q).Fill(0.0)
+	base.Vector(lps.Occupancy).Fill(0.0)
+	base.Vector(lps.Policy).Fill(0.0)
+	lps.InitAbsorbingState()
+	lps.gamma = 1.0
+	lps.constraints = nil
+	lps.Multipliers = nil
+}
+
+// expectedReward returns the expected immediate reward of an action.
+func expectedReward(a *Action) float64 {
+	r := 0.0
+	for _, tr := range a.transitions {
+		r += tr.p * tr.r
+	}
+	return r
+}
+
+// variables returns the index of the LP variable of each state
+// which has actions, or -1 for terminal states, and the number of them.
+func (lps *LPSolver) variables() ([]int, int) {
+	m := lps.model
+	col := make([]int, len(m.states))
+	n := 0
+	for i := range m.states {
+		if len(lps.ToActions(&m.states[i])) == 0 {
+			col[i] = -1
+			continue
+		}
+		col[i] = n
+		n++
+	}
+	return col, n
+}
+
+// SolvePrimal solves the primal program
+// minimizing the sum of V subject to V(s) >= Q(s, a) for every action,
+// and updates V, Q and the greedy deterministic Policy.
+// Occupancy constraints are not used.
+// It returns ErrUnbounded if the value of some state is unbounded below,
+// e.g. a state does not reach an absorbing state with gamma = 1.
+func (lps *LPSolver) SolvePrimal() error {
+	m := lps.model
+	col, n := lps.variables()
+	// V(s) = V+(s) - V-(s) with V+ and V- at 2 col(s) and 2 col(s) + 1
+	c := make([]float64, 2 * n)
+	for j := 0; j < n; j++ {
+		c[2 * j] = -1
+		c[2 * j + 1] = 1
+	}
+	lp := newLinearProgram(c)
+	for i := range m.states {
+		s := &m.states[i]
+		for _, a := range lps.ToActions(s) {
+			coef := make([]float64, 2 * n)
+			coef[2 * col[i]] += 1
+			coef[2 * col[i] + 1] -= 1
+			for _, tr := range a.transitions {
+				k := col[tr.state.index]
+				if k < 0 { continue }
+				coef[2 * k] -= lps.gamma * tr.p
+				coef[2 * k + 1] += lps.gamma * tr.p
+			}
+			lp.addConstraint(coef, GreaterEqual, expectedReward(a))
+		}
+	}
+	x, _, err := lp.maximize()
+	if err != nil {
+		return err
+	}
+	for i := range m.states {
+		lps.V[i] = 0
+		if col[i] >= 0 {
+			lps.V[i] = x[2 * col[i]] - x[2 * col[i] + 1]
+		}
+	}
+	lps.updateQ()
+	lps.UpdatePolicy()
+	return nil
+}
+
+// SolveDual solves the dual program
+// maximizing the expected total reward over occupancy measures starting from initialStateDist
+// subject to the flow conservation and occupancy constraints,
+// and updates Occupancy, Policy and V, which is given by the shadow prices of the flow conservation.
+// With occupancy constraints, Policy may be randomized.
+// States never visited keep their policy, and their V is not necessarily optimal.
+// It returns ErrInfeasible if no occupancy satisfies the constraints.
+func (lps *LPSolver) SolveDual(initialStateDist []float64) error {
+	m := lps.model
+	if len(initialStateDist) != len(m.states) {
+		return &DimensionMismatchError{Got: len(initialStateDist), Want: len(m.states)}
+	}
+	col, _ := lps.variables()
+	// an occupancy variable per action of states with actions
+	actionCol := make([]int, len(m.actions))
+	actionsOfVar := make([]*Action, 0, len(m.actions))
+	for i := range actionCol {
+		actionCol[i] = -1
+	}
+	for i := range m.states {
+		for _, a := range lps.ToActions(&m.states[i]) {
+			actionCol[a.index] = len(actionsOfVar)
+			actionsOfVar = append(actionsOfVar, a)
+		}
+	}
+	n := len(actionsOfVar)
+	c := make([]float64, n)
+	for j, a := range actionsOfVar {
+		c[j] = expectedReward(a)
+	}
+	lp := newLinearProgram(c)
+	rowOf := make([]int, len(m.states))
+	for i := range m.states {
+		rowOf[i] = -1
+		if col[i] < 0 { continue }
+		s := &m.states[i]
+		coef := make([]float64, n)
+		for _, a := range s.actions {
+			coef[actionCol[a.index]] += 1
+		}
+		for _, tr := range s.transitions {
+			if k := actionCol[tr.action.index]; k >= 0 {
+				coef[k] -= lps.gamma * tr.p
+			}
+		}
+		rowOf[i] = len(lp.rows)
+		lp.addConstraint(coef, Equal, initialStateDist[i])
+	}
+	for _, oc := range lps.constraints {
+		coef := make([]float64, n)
+		for a, v := range oc.Coef {
+			if k := actionCol[a]; k >= 0 {
+				coef[k] = v
+			}
+		}
+		lp.addConstraint(coef, oc.Sense, oc.Bound)
+	}
+	x, dual, err := lp.maximize()
+	if err != nil {
+		return err
+	}
+	base.Vector(lps.Occupancy).Fill(0.0)
+	for j, a := range actionsOfVar {
+		lps.Occupancy[a.index] = x[j]
+	}
+	for i := range m.states {
+		lps.V[i] = 0
+		if rowOf[i] >= 0 {
+			lps.V[i] = dual[rowOf[i]]
+		}
+	}
+	lps.Multipliers = append(lps.Multipliers[:0], dual[len(dual) - len(lps.constraints):]...)
+	lps.updateQ()
+	for i := range m.states {
+		actions := lps.ToActions(&m.states[i])
+		z := 0.0
+		for _, a := range actions {
+			z += lps.Occupancy[a.index]
+		}
+		if z <= simplexEps { continue }
+		for _, a := range actions {
+			lps.Policy[a.index] = lps.Occupancy[a.index] / z
+		}
+	}
+	return nil
+}
+
+func (lps *LPSolver) updateQ() {
+	m := lps.model
+	for i := range m.actions {
+		a := &m.actions[i]
+		if a.state == nil { continue }
+		q := 0.0
+		for _, tr := range a.transitions {
+			q += tr.p * (tr.r + lps.gamma * lps.V[tr.state.index])
+		}
+		lps.Q[i] = q
+	}
+}
+
+// StateActionVisitation returns the state visitation frequency derived from Occupancy
+// and a copy of Occupancy, which are comparable with ValueIterator.StateActionVisitation
+// for the same initialStateDist.
+func (lps *LPSolver) StateActionVisitation(initialStateDist []float64) ([]float64, []float64) {
+	m := lps.model
+	stateDist := make([]float64, len(m.states))
+	copy(stateDist, initialStateDist)
+	for i := range m.states {
+		s := &m.states[i]
+		for _, tr := range s.transitions {
+			if lps.isAbsorbing[tr.action.state.index] { continue }
+			stateDist[i] += lps.gamma * tr.p * lps.Occupancy[tr.action.index]
+		}
+	}
+	actionDist := make([]float64, len(m.actions))
+	copy(actionDist, lps.Occupancy)
+	return stateDist, actionDist
+}
+
+func (lps *LPSolver) String() string {
+	s := fmt.Sprintf("V: %v\n", lps.V)
+	s += fmt.Sprintf("Occupancy: %v\n", lps.Occupancy)
+	s += fmt.Sprintf("Policy: %v\n", lps.Policy)
+	return s
+}
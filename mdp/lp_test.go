@@ -0,0 +1,114 @@
+package mdp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLPSolver(t *testing.T) {
+	sm, _ := NewStochasticModel(
+		[]int{0, 1, 2},
+		[]StochasticTransition{
+			{FromID: 0, Outcomes: []Outcome{{2, 1}}, Reward: -3},
+			{FromID: 0, Outcomes: []Outcome{{2, 0.5}, {1, 0.5}}, Reward: -1},
+			{FromID: 1, Outcomes: []Outcome{{2, 1}}, Reward: -1},
+		},
+	)
+	vi := NewValueIterator(sm)
+	vi.SetAbsorbingState(2)
+	vi.RunValueIteration()
+	vi.UpdatePolicy()
+	initialStateDist := []float64{1, 0, 0}
+	wantStateDist, wantActionDist := vi.StateActionVisitation(initialStateDist)
+
+	lps := NewLPSolver(sm)
+	lps.SetAbsorbingState(2)
+	if err := lps.SolvePrimal(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range vi.V {
+		if math.Abs(lps.V[i] - vi.V[i]) > 1e-9 {
+			t.Errorf("primal V@%d: got %.3f, want %.3f", i, lps.V[i], vi.V[i])
+		}
+	}
+	for i := range vi.Policy {
+		if lps.Policy[i] != vi.Policy[i] {
+			t.Errorf("primal Policy@%d: got %.3f, want %.3f", i, lps.Policy[i], vi.Policy[i])
+		}
+	}
+
+	if err := lps.SolveDual(initialStateDist); err != nil {
+		t.Fatal(err)
+	}
+	stateDist, actionDist := lps.StateActionVisitation(initialStateDist)
+	for i := range wantStateDist {
+		if math.Abs(stateDist[i] - wantStateDist[i]) > 1e-9 {
+			t.Errorf("stateDist@%d: got %.3f, want %.3f", i, stateDist[i], wantStateDist[i])
+		}
+	}
+	for i := range wantActionDist {
+		if math.Abs(actionDist[i] - wantActionDist[i]) > 1e-9 {
+			t.Errorf("actionDist@%d: got %.3f, want %.3f", i, actionDist[i], wantActionDist[i])
+		}
+	}
+	if math.Abs(lps.V[0] - vi.V[0]) > 1e-9 {
+		t.Errorf("dual V@0: got %.3f, want %.3f", lps.V[0], vi.V[0])
+	}
+}
+
+func TestDiscountedLPSolver(t *testing.T) {
+	gm := newGridModel(6, 5)
+	vi := NewValueIterator(gm)
+	vi.SetGamma(0.9)
+	vi.SetAbsorbingState(0)
+	vi.RunValueIteration()
+
+	lps := NewLPSolver(gm)
+	lps.SetGamma(0.9)
+	lps.SetAbsorbingState(0)
+	if err := lps.SolvePrimal(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range vi.V {
+		if math.Abs(lps.V[i] - vi.V[i]) > 1e-2 {
+			t.Errorf("V@%d: got %.4f, want %.4f", i, lps.V[i], vi.V[i])
+		}
+	}
+}
+
+func TestOccupancyConstraint(t *testing.T) {
+	// 0 reaches 2 directly at cost 1 or through 1 at cost 2.
+	cm, _ := NewModel([]int{0, 1, 2}, []StateTransition{
+		{0, 2, -1},
+		{0, 1, -1},
+		{1, 2, -1},
+	})
+	direct, _ := cm.ActionByID(0, 2)
+	lps := NewLPSolver(cm)
+	lps.SetAbsorbingState(2)
+	coef := make([]float64, cm.NumActions())
+	coef[direct.Index()] = 1
+	if err := lps.AddOccupancyConstraint(OccupancyConstraint{coef, LessEqual, 0.25}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lps.SolveDual([]float64{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(lps.Policy[direct.Index()] - 0.25) > 1e-9 {
+		t.Errorf("policy: got %v, want 0.25 on the direct action", lps.Policy)
+	}
+	total := 0.0
+	for i, x := range lps.Occupancy {
+		total += x * expectedReward(&cm.actions[i])
+	}
+	if math.Abs(total + 1.75) > 1e-9 {
+		t.Errorf("total reward: got %.3f, want -1.75", total)
+	}
+
+	lps.Init()
+	lps.SetAbsorbingState(2)
+	lps.AddOccupancyConstraint(OccupancyConstraint{coef, GreaterEqual, 2})
+	if err := lps.SolveDual([]float64{1, 0, 0}); err != ErrInfeasible {
+		t.Errorf("got %v, want %v", err, ErrInfeasible)
+	}
+}
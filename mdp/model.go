@@ -6,6 +6,8 @@ type Model struct {
 	states []State
 	actions []Action
 	transitions []Transition
+	numCosts int // number of auxiliary costs of each action
+	numObjectives int // number of elements of vector rewards
 }
 
 // NumStates returns a number of states in MDP.
@@ -19,15 +21,35 @@ func (m *Model) NumActions() int {
 }
 
 // UpdateReward update the reward of all actions.
-func (m *Model) UpdateReward(reward []float64) bool {
+func (m *Model) UpdateReward(reward []float64) error {
 	if len(reward) != m.NumActions() {
-		return false
+		return &DimensionMismatchError{Got: len(reward), Want: m.NumActions()}
 	}
 	for i := range m.actions {
-		if m.actions[i].transition == nil { continue }
-		m.actions[i].transition.r = reward[i]
+		for _, tr := range m.actions[i].transitions {
+			tr.r = reward[i]
+		}
 	}
-	return true
+	return nil
+}
+
+// UpdateActionReward updates the reward of given actions only.
+// It returns *UnknownActionError for an action dropped as a dangling transition.
+func (m *Model) UpdateActionReward(actionIndices []int, reward []float64) error {
+	if len(reward) != len(actionIndices) {
+		return &DimensionMismatchError{Got: len(reward), Want: len(actionIndices)}
+	}
+	for _, i := range actionIndices {
+		if i < 0 || i >= len(m.actions) || m.actions[i].state == nil {
+			return &UnknownActionError{i}
+		}
+	}
+	for k, i := range actionIndices {
+		for _, tr := range m.actions[i].transitions {
+			tr.r = reward[k]
+		}
+	}
+	return nil
 }
 
 // State represents a state of Model.
@@ -38,6 +60,11 @@ type State struct {
 	transitions []*Transition
 }
 
+// ID returns the ID of the state.
+func (s *State) ID() int {
+	return s.id
+}
+
 // Index returns the array index of the state.
 func (s *State) Index() int {
 	return s.index
@@ -47,7 +74,9 @@ func (s *State) Index() int {
 type Action struct {
 	index int
 	state *State
-	transition *Transition
+	transitions []*Transition
+	costs []float64 // auxiliary costs
+	rewards []float64 // vector reward
 }
 
 // Index returns the array index of the action.
@@ -56,11 +85,12 @@ func (a *Action) Index() int {
 }
 
 // Transition represents a action-state transition of Model.
-// Since the Model is deterministic, an action corresponds to the state one-to-one.
+// If the Model is deterministic, an action corresponds to the state one-to-one.
 // If stochastic Model, Action has multiple transitions and their probability.
 type Transition struct {
 	action *Action
 	state *State
+	p float64 // probability
 	r float64 // reward
 }
 
@@ -70,8 +100,48 @@ type StateTransition struct {
 	Reward float64 // reward
 }
 
-// NewModel constructs a Model instance and returns a pointer to it.
-func NewModel(stateIDs []int, stateTransitions []StateTransition) *Model {
+// Outcome is a possible next state of a StochasticTransition.
+type Outcome struct {
+	ToID int `json:"to"`
+	Probability float64 `json:"p"`
+}
+
+// StochasticTransition is an action in a stochastic Model
+// which leads to one of the outcome states with its probability.
+// Costs are auxiliary costs of the action, e.g. fuel and tolls, which may be budgeted.
+// Rewards is a vector reward of the action, e.g. travel time, distance and comfort,
+// for multi-objective planning.
+type StochasticTransition struct {
+	FromID int `json:"from"`
+	Outcomes []Outcome `json:"outcomes"`
+	Reward float64 `json:"reward"`
+	Costs []float64 `json:"costs,omitempty"`
+	Rewards []float64 `json:"rewards,omitempty"`
+}
+
+// NewModel constructs a deterministic Model instance and returns a pointer to it.
+// Transitions referencing unknown state IDs are dropped
+// and reported by *DanglingTransitionError along with the Model.
+func NewModel(stateIDs []int, stateTransitions []StateTransition) (*Model, error) {
+	stochasticTransitions := make([]StochasticTransition, len(stateTransitions))
+	for i, st := range stateTransitions {
+		stochasticTransitions[i] = StochasticTransition{
+			FromID: st.FromID,
+			Outcomes: []Outcome{{ToID: st.ToID, Probability: 1}},
+			Reward: st.Reward,
+		}
+	}
+	return NewStochasticModel(stateIDs, stochasticTransitions)
+}
+
+// NewStochasticModel constructs a Model instance whose actions
+// may have multiple outcomes and returns a pointer to it.
+// Probabilities of the outcomes of each action should sum to 1.
+// Transitions referencing unknown state IDs or without outcomes are dropped
+// and reported by *DanglingTransitionError along with the Model.
+// Every action has as many auxiliary costs as the longest Costs, padded with 0,
+// and so do vector rewards.
+func NewStochasticModel(stateIDs []int, stochasticTransitions []StochasticTransition) (*Model, error) {
 	// construct stateID => stateIdx Map
 	StateOf := make(map[int]*State)
 	states := make([]State, len(stateIDs))
@@ -84,48 +154,102 @@ func NewModel(stateIDs []int, stateTransitions []StateTransition) *Model {
 		}
 		StateOf[id] = &states[i]
 	}
-	actions := make([]Action, len(stateTransitions))
-	transitions := make([]Transition, len(stateTransitions))
-	for i, st := range stateTransitions {
-		toState, ok := StateOf[st.ToID]
-		if !ok {
-			continue
+	numTransitions := 0
+	numCosts := 0
+	numObjectives := 0
+	for _, st := range stochasticTransitions {
+		numTransitions += len(st.Outcomes)
+		if len(st.Costs) > numCosts {
+			numCosts = len(st.Costs)
+		}
+		if len(st.Rewards) > numObjectives {
+			numObjectives = len(st.Rewards)
 		}
-		state, ok := StateOf[st.FromID]
-		if !ok {
+	}
+	actions := make([]Action, len(stochasticTransitions))
+	transitions := make([]Transition, numTransitions)
+	k := 0
+	var dangling []DanglingTransition
+	for i, st := range stochasticTransitions {
+		unknownIDs := findUnknownIDs(StateOf, st)
+		if len(unknownIDs) > 0 || len(st.Outcomes) == 0 {
+			dangling = append(dangling, DanglingTransition{
+				Index: i,
+				FromID: st.FromID,
+				UnknownIDs: unknownIDs,
+			})
 			continue
 		}
+		state := StateOf[st.FromID]
 		actions[i].state = state
 		actions[i].index = i
-		actions[i].transition = &transitions[i]
-		transitions[i].state = toState
-		transitions[i].action = &actions[i]
-		transitions[i].r = st.Reward
+		actions[i].transitions = make([]*Transition, len(st.Outcomes))
+		if numCosts > 0 {
+			actions[i].costs = make([]float64, numCosts)
+			copy(actions[i].costs, st.Costs)
+		}
+		if numObjectives > 0 {
+			actions[i].rewards = make([]float64, numObjectives)
+			copy(actions[i].rewards, st.Rewards)
+		}
+		for j, o := range st.Outcomes {
+			toState := StateOf[o.ToID]
+			transitions[k].state = toState
+			transitions[k].action = &actions[i]
+			transitions[k].p = o.Probability
+			transitions[k].r = st.Reward
+			actions[i].transitions[j] = &transitions[k]
+			toState.transitions = append(toState.transitions, &transitions[k])
+			k++
+		}
 		state.actions = append(state.actions, &actions[i])
-		toState.transitions = append(toState.transitions, &transitions[i])
 	}
 
 	m := &Model{
 		states: states,
 		actions: actions,
-		transitions: transitions,
+		transitions: transitions[:k],
 		StateOf: StateOf,
+		numCosts: numCosts,
+		numObjectives: numObjectives,
 	}
-	return m
+	if len(dangling) > 0 {
+		return m, &DanglingTransitionError{Transitions: dangling}
+	}
+	return m, nil
+}
+
+func findUnknownIDs(StateOf map[int]*State, st StochasticTransition) (ids []int) {
+	if _, ok := StateOf[st.FromID]; !ok {
+		ids = append(ids, st.FromID)
+	}
+	for _, o := range st.Outcomes {
+		if _, ok := StateOf[o.ToID]; !ok {
+			ids = append(ids, o.ToID)
+		}
+	}
+	return
 }
 
 // ActionByID returns the action satisfied with a given state transition.
-func (m *Model) ActionByID(fromStateID, toStateID int) (a *Action, ok bool) {
+// If several actions may lead to the state, the one with the highest probability is returned.
+func (m *Model) ActionByID(fromStateID, toStateID int) (*Action, error) {
 	fromState, ok := m.StateOf[fromStateID]
-	if !ok { return }
+	if !ok { return nil, &UnknownStateError{fromStateID} }
 	toState, ok := m.StateOf[toStateID]
-	if !ok { return }
+	if !ok { return nil, &UnknownStateError{toStateID} }
+	var a *Action
+	maxP := 0.0
 	for _, action := range fromState.actions {
-		if action.transition.state == toState {
-			a = action
-			ok = true
-			return
+		for _, tr := range action.transitions {
+			if tr.state == toState && tr.p > maxP {
+				a = action
+				maxP = tr.p
+			}
 		}
 	}
-	return
+	if a == nil {
+		return nil, &NoTransitionError{FromID: fromStateID, ToID: toStateID}
+	}
+	return a, nil
 }
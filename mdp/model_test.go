@@ -0,0 +1,114 @@
+package mdp
+
+import (
+	"testing"
+)
+
+func TestNewModelDanglingTransition(t *testing.T) {
+	dm, err := NewModel(
+		[]int{0, 1},
+		[]StateTransition{{0, 1, -1}, {0, 5, -1}, {7, 1, -1}},
+	)
+	de, ok := err.(*DanglingTransitionError)
+	if !ok {
+		t.Fatalf("got %v, want *DanglingTransitionError", err)
+	}
+	wants := []DanglingTransition{{1, 0, []int{5}}, {2, 7, []int{7}}}
+	if len(de.Transitions) != len(wants) {
+		t.Fatalf("got %v, want %v", de.Transitions, wants)
+	}
+	for i, want := range wants {
+		got := de.Transitions[i]
+		if got.Index != want.Index || got.FromID != want.FromID || got.UnknownIDs[0] != want.UnknownIDs[0] {
+			t.Errorf("@%d: got %v, want %v", i, got, want)
+		}
+	}
+	if _, err := dm.ActionByID(0, 1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestModelErrors(t *testing.T) {
+	if err := m.UpdateReward([]float64{0}); err == nil {
+		t.Errorf("UpdateReward: want *DimensionMismatchError")
+	}
+	if _, err := m.ActionByID(0, 9); err == nil {
+		t.Errorf("ActionByID: want *UnknownStateError")
+	}
+	if _, err := m.ActionByID(0, 2); err == nil {
+		t.Errorf("ActionByID: want *NoTransitionError")
+	}
+
+	evi := NewValueIterator(m)
+	if err := evi.SetAbsorbingState(9); err == nil {
+		t.Errorf("SetAbsorbingState: want *UnknownStateError")
+	}
+	evi.SetAbsorbingState(2)
+	evi.SetAbsorbingState(3)
+	evi.RunValueIteration()
+	evi.UpdatePolicy()
+	if _, err := evi.GenerateTrajectory(0, 2, 10); err == nil {
+		t.Errorf("GenerateTrajectory: want *DeadEndError")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// 0 <-> 1 -> 2 -> 3, 4 is isolated and 0 -> 1 is duplicated
+	vm, _ := NewStochasticModel(
+		[]int{0, 1, 2, 3, 4},
+		[]StochasticTransition{
+			{FromID: 0, Outcomes: []Outcome{{1, 1}}},
+			{FromID: 0, Outcomes: []Outcome{{1, 1}}},
+			{FromID: 1, Outcomes: []Outcome{{0, 1}}},
+			{FromID: 1, Outcomes: []Outcome{{2, 0.5}, {1, 0.4}}},
+			{FromID: 2, Outcomes: []Outcome{{3, 1}}},
+		},
+	)
+	r, err := vm.Validate([]int{3}, []float64{1, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equal := func(got, want []int) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+	if !equal(r.DeadEnds, []int{3, 4}) {
+		t.Errorf("DeadEnds: got %v", r.DeadEnds)
+	}
+	if !equal(r.NotReachingGoal, []int{4}) {
+		t.Errorf("NotReachingGoal: got %v", r.NotReachingGoal)
+	}
+	if !equal(r.Unreachable, []int{4}) {
+		t.Errorf("Unreachable: got %v", r.Unreachable)
+	}
+	if len(r.DuplicateActions) != 1 || !equal(r.DuplicateActions[0], []int{0, 1}) {
+		t.Errorf("DuplicateActions: got %v", r.DuplicateActions)
+	}
+	if !equal(r.InvalidProbabilities, []int{3}) {
+		t.Errorf("InvalidProbabilities: got %v", r.InvalidProbabilities)
+	}
+	if len(r.Components) != 4 || !equal(r.Components[0], []int{3}) || len(r.Components[2]) != 2 {
+		t.Errorf("Components: got %v", r.Components)
+	}
+	if r.OK() {
+		t.Errorf("OK: got true")
+	}
+	chain, _ := NewModel([]int{0, 1}, []StateTransition{{0, 1, -1}})
+	if r, _ := chain.Validate(nil, nil); r.OK() {
+		t.Errorf("OK with a dead end: got true")
+	}
+	if r, _ := chain.Validate([]int{1}, nil); !r.OK() {
+		t.Errorf("OK with a dead end at the goal: got false")
+	}
+
+	if c := m.StronglyConnectedComponents(); len(c) != 1 || len(c[0]) != 4 {
+		t.Errorf("Components: got %v", c)
+	}
+}
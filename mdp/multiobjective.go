@@ -0,0 +1,328 @@
+package mdp
+
+import (
+	"context"
+	"math"
+)
+
+const (
+	minHullImprovement = 1e-6 // relative improvement of a scalarized value to extend the hull
+	cornerEps = 1e-9
+)
+
+// NumObjectives returns a number of elements of vector rewards.
+func (m *Model) NumObjectives() int {
+	return m.numObjectives
+}
+
+// ObjectiveRewards returns the k-th element of the vector rewards of all actions.
+func (m *Model) ObjectiveRewards(k int) ([]float64, error) {
+	if k < 0 || k >= m.numObjectives {
+		return nil, &UnknownObjectiveError{k}
+	}
+	rewards := make([]float64, len(m.actions))
+	for i := range m.actions {
+		if m.actions[i].rewards != nil {
+			rewards[i] = m.actions[i].rewards[k]
+		}
+	}
+	return rewards, nil
+}
+
+// SetObjectiveRewards replaces vector rewards of all actions,
+// where rewards[k] is the k-th objective of each action, e.g. for a Model constructed by NewModel.
+func (m *Model) SetObjectiveRewards(rewards [][]float64) error {
+	for _, r := range rewards {
+		if len(r) != len(m.actions) {
+			return &DimensionMismatchError{Got: len(r), Want: len(m.actions)}
+		}
+	}
+	m.numObjectives = len(rewards)
+	for i := range m.actions {
+		a := &m.actions[i]
+		a.rewards = nil
+		if m.numObjectives == 0 || a.state == nil { continue }
+		a.rewards = make([]float64, m.numObjectives)
+		for k, r := range rewards {
+			a.rewards[k] = r[i]
+		}
+	}
+	return nil
+}
+
+// ParetoPolicy is a deterministic policy on the convex hull of a multi-objective Model.
+type ParetoPolicy struct {
+	Weights []float64 // weights of objectives for which the policy is optimal
+	Values []float64 // expected total of each objective
+	Policy []float64
+}
+
+// MultiObjectiveSolver computes the convex coverage set of a Model with vector rewards,
+// i.e. the deterministic policies which are optimal for some linear weighting of the objectives,
+// by Optimistic Linear Support over weight vectors with ValueIterator.
+// With gamma = 1, every objective should be negative, i.e. a cost,
+// so that any weighting makes policies reach absorbing states.
+type MultiObjectiveSolver struct {
+	model *Model
+	goalIDs []int
+	gamma float64 // discount factor
+	opts Options
+}
+
+// NewMultiObjectiveSolver constructs a MultiObjectiveSolver instance from a given Model.
+func NewMultiObjectiveSolver(model *Model) *MultiObjectiveSolver {
+	return &MultiObjectiveSolver{
+		model: model,
+		gamma: 1.0,
+		opts: DefaultOptions(),
+	}
+}
+
+// SetAbsorbingState sets absorbing states in the Model.
+func (mo *MultiObjectiveSolver) SetAbsorbingState(stateID int) error {
+	if _, ok := mo.model.StateOf[stateID]; !ok {
+		return &UnknownStateError{stateID}
+	}
+	mo.goalIDs = append(mo.goalIDs, stateID)
+	return nil
+}
+
+// InitAbsorbingState initializes absorbing states.
+func (mo *MultiObjectiveSolver) InitAbsorbingState() {
+	mo.goalIDs = nil
+}
+
+// SetGamma sets a discount factor.
+func (mo *MultiObjectiveSolver) SetGamma(gamma float64) {
+	mo.gamma = gamma
+}
+
+// SetOptions sets convergence settings of Value Iteration.
+// It returns *InvalidOptionError if they are invalid.
+func (mo *MultiObjectiveSolver) SetOptions(opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	mo.opts = opts
+	return nil
+}
+
+// ConvexCoverageSet returns the policies on the convex hull of expected totals of objectives
+// from initialStateDist, which are Pareto optimal.
+// Pareto optimal policies inside the hull are not found.
+// Weighted sums of rewards are solved on a copy of the Model, which is left unchanged.
+func (mo *MultiObjectiveSolver) ConvexCoverageSet(initialStateDist []float64) ([]ParetoPolicy, error) {
+	return mo.ConvexCoverageSetContext(context.Background(), initialStateDist)
+}
+
+// ConvexCoverageSetContext runs ConvexCoverageSet until completion or cancellation of ctx.
+// If ctx is done, it returns ctx.Err() with the policies found so far.
+func (mo *MultiObjectiveSolver) ConvexCoverageSetContext(ctx context.Context, initialStateDist []float64) ([]ParetoPolicy, error) {
+	m := mo.model
+	d := m.numObjectives
+	if d == 0 {
+		return nil, &UnknownObjectiveError{0}
+	}
+	if len(initialStateDist) != len(m.states) {
+		return nil, &DimensionMismatchError{Got: len(initialStateDist), Want: len(m.states)}
+	}
+	objectives := make([][]float64, d)
+	for k := range objectives {
+		objectives[k], _ = m.ObjectiveRewards(k)
+	}
+	// scalarized rewards are set on a copy so that the Model is never modified
+	scalarized, err := newModelFromEncoding(m.StateIDs(), m.StochasticTransitions())
+	if err != nil {
+		return nil, err
+	}
+
+	vi := NewValueIteratorWithOptions(scalarized, mo.opts)
+	reward := make([]float64, len(m.actions))
+	var hull []ParetoPolicy
+	var checked [][]float64
+	queue := make([][]float64, d)
+	for k := range queue {
+		queue[k] = make([]float64, d)
+		queue[k][k] = 1
+	}
+	for len(queue) > 0 {
+		w := queue[0]
+		queue = queue[1:]
+		if containsWeights(checked, w) { continue }
+		checked = append(checked, w)
+
+		for i := range reward {
+			reward[i] = 0
+			for k, r := range objectives {
+				reward[i] += w[k] * r[i]
+			}
+		}
+		scalarized.UpdateReward(reward)
+		vi.Init()
+		vi.SetGamma(mo.gamma)
+		for _, id := range mo.goalIDs {
+			vi.SetAbsorbingState(id)
+		}
+		if _, err := vi.RunValueIterationContext(ctx); err != nil {
+			return hull, err
+		}
+		vi.UpdatePolicy()
+		_, actionDist, err := vi.StateActionVisitationContext(ctx, initialStateDist)
+		if err != nil {
+			return hull, err
+		}
+		values := make([]float64, d)
+		for k, r := range objectives {
+			for i, x := range actionDist {
+				values[k] += x * r[i]
+			}
+		}
+
+		best := math.Inf(-1)
+		for _, p := range hull {
+			best = math.Max(best, dot(w, p.Values))
+		}
+		if len(hull) > 0 && dot(w, values) <= best + minHullImprovement * (1 + math.Abs(best)) {
+			continue
+		}
+		hull = append(hull, ParetoPolicy{
+			Weights: w,
+			Values: values,
+			Policy: append([]float64(nil), vi.Policy...),
+		})
+		points := make([][]float64, len(hull))
+		for i, p := range hull {
+			points[i] = p.Values
+		}
+		queue = queue[:0]
+		for _, c := range cornerWeights(points, d) {
+			if !containsWeights(checked, c) {
+				queue = append(queue, c)
+			}
+		}
+	}
+	return hull, nil
+}
+
+func dot(a, b []float64) float64 {
+	s := 0.0
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+func containsWeights(ws [][]float64, w []float64) bool {
+	for _, v := range ws {
+		same := true
+		for i := range v {
+			if math.Abs(v[i] - w[i]) > 1e-6 {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
+
+// cornerWeights returns the vertices of the piecewise linear function
+// max_i w . points[i] over the simplex of weights w of d objectives.
+// Each vertex is where d of the hyperplanes w . points[i] = y and w_j = 0 meet with sum of w = 1.
+func cornerWeights(points [][]float64, d int) [][]float64 {
+	numPlanes := len(points) + d
+	corners := make([][]float64, 0)
+	combination := make([]int, d)
+	for i := range combination {
+		combination[i] = i
+	}
+	a := make([][]float64, d + 1)
+	b := make([]float64, d + 1)
+	for {
+		// unknowns are w_0, ..., w_{d-1} and y
+		for r, plane := range combination {
+			a[r] = make([]float64, d + 1)
+			b[r] = 0
+			if plane < len(points) {
+				copy(a[r], points[plane])
+				a[r][d] = -1
+			} else {
+				a[r][plane - len(points)] = 1
+			}
+		}
+		a[d] = make([]float64, d + 1)
+		for j := 0; j < d; j++ {
+			a[d][j] = 1
+		}
+		b[d] = 1
+		if x, ok := solveDense(a, b); ok && isCorner(x, points, d) && !containsWeights(corners, x[:d]) {
+			corners = append(corners, x[:d])
+		}
+		// next combination in lexicographic order
+		i := d - 1
+		for i >= 0 && combination[i] == numPlanes - d + i {
+			i--
+		}
+		if i < 0 {
+			break
+		}
+		combination[i]++
+		for j := i + 1; j < d; j++ {
+			combination[j] = combination[j - 1] + 1
+		}
+	}
+	return corners
+}
+
+// isCorner reports whether a solution (w, y) lies on the upper surface over the simplex.
+func isCorner(x []float64, points [][]float64, d int) bool {
+	for j := 0; j < d; j++ {
+		if x[j] < -cornerEps {
+			return false
+		}
+		x[j] = math.Max(x[j], 0)
+	}
+	for _, p := range points {
+		if dot(x[:d], p) > x[d] + cornerEps * (1 + math.Abs(x[d])) {
+			return false
+		}
+	}
+	return true
+}
+
+// solveDense solves ax = b by Gaussian elimination with partial pivoting.
+// a and b are overwritten. It returns false if a is singular.
+func solveDense(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < cornerEps {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			for c := col; c < n; c++ {
+				a[r][c] -= f * a[col][c]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		s := b[r]
+		for c := r + 1; c < n; c++ {
+			s -= a[r][c] * x[c]
+		}
+		x[r] = s / a[r][r]
+	}
+	return x, true
+}
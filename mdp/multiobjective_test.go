@@ -0,0 +1,52 @@
+package mdp
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestConvexCoverageSet(t *testing.T) {
+	// routes from 0 to 3 with (time, distance) costs:
+	// direct (-5, -1), via 1 (-2, -4), via 2 (-3, -2) and via 4 (-4, -3) inside the hull.
+	mm, _ := NewStochasticModel([]int{0, 1, 2, 3, 4}, []StochasticTransition{
+		{FromID: 0, Outcomes: []Outcome{{3, 1}}, Reward: -1, Rewards: []float64{-5, -1}},
+		{FromID: 0, Outcomes: []Outcome{{1, 1}}, Reward: -1, Rewards: []float64{-1, -2}},
+		{FromID: 1, Outcomes: []Outcome{{3, 1}}, Reward: -1, Rewards: []float64{-1, -2}},
+		{FromID: 0, Outcomes: []Outcome{{2, 1}}, Reward: -1, Rewards: []float64{-1.5, -1}},
+		{FromID: 2, Outcomes: []Outcome{{3, 1}}, Reward: -1, Rewards: []float64{-1.5, -1}},
+		{FromID: 0, Outcomes: []Outcome{{4, 1}}, Reward: -1, Rewards: []float64{-2, -1.5}},
+		{FromID: 4, Outcomes: []Outcome{{3, 1}}, Reward: -1, Rewards: []float64{-2, -1.5}},
+	})
+	mo := NewMultiObjectiveSolver(mm)
+	mo.SetAbsorbingState(3)
+	hull, err := mo.ConvexCoverageSet([]float64{1, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(hull, func(i, j int) bool { return hull[i].Values[0] < hull[j].Values[0] })
+	want := [][]float64{{-5, -1}, {-3, -2}, {-2, -4}}
+	if len(hull) != len(want) {
+		t.Fatalf("hull: got %d policies %v, want %v", len(hull), hull, want)
+	}
+	for i, p := range hull {
+		for k := range want[i] {
+			if math.Abs(p.Values[k] - want[i][k]) > 1e-3 {
+				t.Errorf("policy %d: got %v, want %v", i, p.Values, want[i])
+			}
+		}
+	}
+	for i := range mm.transitions {
+		if mm.transitions[i].r != -1 {
+			t.Errorf("reward of transition %d is modified: %.3f", i, mm.transitions[i].r)
+		}
+	}
+
+	dm, _ := NewModel([]int{0, 1}, []StateTransition{{0, 1, -1}})
+	if _, err := NewMultiObjectiveSolver(dm).ConvexCoverageSet([]float64{1, 0}); err == nil {
+		t.Errorf("got nil, want *UnknownObjectiveError")
+	}
+	if err := dm.SetObjectiveRewards([][]float64{{-1}, {-2}}); err != nil || dm.NumObjectives() != 2 {
+		t.Errorf("SetObjectiveRewards: got %v with %d objectives", err, dm.NumObjectives())
+	}
+}
@@ -0,0 +1,95 @@
+package mdp
+
+import (
+	"fmt"
+	"math"
+)
+
+// Options represents convergence settings of ValueIterator.
+type Options struct {
+	MinTDError float64 // TD error threshold of Value Iteration at the last annealing stage
+	MinSDError float64 // state distribution error threshold of StateActionVisitation at the last annealing stage
+	NumAnnealing int // number of annealing stages
+	AnnealingRate float64 // rate by which the threshold is multiplied at each annealing stage
+	QueueCapacity int // maximum number of states in the priority queue, unlimited if 0
+	MaxIterations int // maximum number of iterations in each annealing stage
+}
+
+// DefaultOptions returns the default convergence settings.
+func DefaultOptions() Options {
+	return Options{
+		MinTDError: minTDError,
+		MinSDError: minSDError,
+		NumAnnealing: numAnnealing,
+		AnnealingRate: annealingRate,
+		QueueCapacity: 0,
+		MaxIterations: maxIterations,
+	}
+}
+
+// Validate returns *InvalidOptionError for the first setting
+// with which the annealing stages would not converge as intended.
+func (o Options) Validate() error {
+	switch {
+	case !(o.MinTDError > 0):
+		return &InvalidOptionError{"MinTDError", o.MinTDError}
+	case !(o.MinSDError > 0):
+		return &InvalidOptionError{"MinSDError", o.MinSDError}
+	case o.NumAnnealing < 1:
+		return &InvalidOptionError{"NumAnnealing", float64(o.NumAnnealing)}
+	case !(o.AnnealingRate > 0 && o.AnnealingRate < 1):
+		return &InvalidOptionError{"AnnealingRate", o.AnnealingRate}
+	case o.MaxIterations < 1:
+		return &InvalidOptionError{"MaxIterations", float64(o.MaxIterations)}
+	}
+	return nil
+}
+
+// convergence holds the convergence settings which solvers embed.
+type convergence struct {
+	opts Options
+}
+
+// SetOptions sets convergence settings.
+// It returns *InvalidOptionError if they are invalid.
+func (c *convergence) SetOptions(opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	c.opts = opts
+	return nil
+}
+
+// initialThreshold returns the threshold at the first annealing stage
+// which anneals to a given minimum threshold at the last stage.
+func (o *Options) initialThreshold(minThreshold float64) float64 {
+	return minThreshold * math.Pow(o.AnnealingRate, -float64(o.NumAnnealing - 1))
+}
+
+// hasRoom reports whether another state can be pushed into the priority queue.
+func (o *Options) hasRoom(pq *PriorityQueue) bool {
+	return o.QueueCapacity <= 0 || pq.Size() < o.QueueCapacity
+}
+
+// ConvergenceReport represents how Value Iteration converged.
+type ConvergenceReport struct {
+	Iterations []int // number of iterations in each annealing stage
+	Residual float64 // the largest TD error in a full sweep after the last annealing stage
+	HitMaxIterations bool // whether any annealing stage reached MaxIterations
+}
+
+func (r ConvergenceReport) String() string {
+	return fmt.Sprintf("{ iters: %v, residual: %.6f, capped: %t }", r.Iterations, r.Residual, r.HitMaxIterations)
+}
+
+// Progress represents the state of a solver at the end of an annealing stage.
+type Progress struct {
+	Stage int // annealing stage
+	Iterations int // number of iterations in the stage
+	Threshold float64 // error threshold of the stage
+	Residual float64 // the last error observed in the stage
+	Remaining int // number of states left in the priority queue
+}
+
+// ProgressFunc is a hook which receives the progress of a solver.
+type ProgressFunc func(Progress)
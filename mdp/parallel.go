@@ -0,0 +1,128 @@
+package mdp
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// partition is a contiguous range of state indices assigned to a worker.
+type partition struct {
+	lo, hi int
+}
+
+// partitionStates splits states into numWorkers contiguous ranges
+// balanced by the number of outcomes to back up.
+func (m *Model) partitionStates(numWorkers int) []partition {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	total := 0
+	for i := range m.states {
+		total += m.states[i].numOutcomes() + 1
+	}
+	parts := make([]partition, 0, numWorkers)
+	lo, load := 0, 0
+	for i := range m.states {
+		load += m.states[i].numOutcomes() + 1
+		if load * numWorkers >= total * (len(parts) + 1) && len(parts) < numWorkers - 1 {
+			parts = append(parts, partition{lo, i + 1})
+			lo = i + 1
+		}
+	}
+	return append(parts, partition{lo, len(m.states)})
+}
+
+func (s *State) numOutcomes() int {
+	n := 0
+	for _, a := range s.actions {
+		n += len(a.transitions)
+	}
+	return n
+}
+
+// RunParallelValueIteration runs Value Iteration with numWorkers goroutines and updates V and Q.
+// States are partitioned into contiguous ranges, and each sweep backs up the ranges concurrently.
+// Within its range a worker updates V in place, while values of the other ranges
+// are read from the previous sweep, so the result is deterministic for a given numWorkers.
+// It stops when the largest TD error in a sweep falls below MinTDError,
+// and Iterations of the report holds the number of sweeps.
+func (vi *ValueIterator) RunParallelValueIteration(numWorkers int) ConvergenceReport {
+	report, _ := vi.RunParallelValueIterationContext(context.Background(), numWorkers)
+	return report
+}
+
+// RunParallelValueIterationContext runs RunParallelValueIteration until convergence or cancellation of ctx.
+// If ctx is done, it returns ctx.Err() leaving V and Q partially updated.
+func (vi *ValueIterator) RunParallelValueIterationContext(ctx context.Context, numWorkers int) (ConvergenceReport, error) {
+	m := vi.model
+	opts := &vi.opts
+	parts := m.partitionStates(numWorkers)
+	cur := make([]float64, len(vi.V))
+	next := make([]float64, len(vi.V))
+	copy(cur, vi.V)
+	residuals := make([]float64, len(parts))
+	report := ConvergenceReport{Iterations: make([]int, 1)}
+	wg := sync.WaitGroup{}
+	var k int
+	for k = 0; k < opts.MaxIterations; k++ {
+		if ctx.Err() != nil {
+			copy(vi.V, cur)
+			report.Iterations[0] = k
+			return report, ctx.Err()
+		}
+		for w, part := range parts {
+			wg.Add(1)
+			go func(w int, part partition) {
+				defer wg.Done()
+				residuals[w] = vi.sweepPartition(part, cur, next)
+			}(w, part)
+		}
+		wg.Wait()
+		cur, next = next, cur
+		report.Residual = 0
+		for _, r := range residuals {
+			report.Residual = math.Max(report.Residual, r)
+		}
+		if vi.progress != nil {
+			vi.progress(Progress{Stage: 0, Iterations: k + 1, Threshold: opts.MinTDError, Residual: report.Residual})
+		}
+		if report.Residual < opts.MinTDError {
+			k++
+			break
+		}
+	}
+	copy(vi.V, cur)
+	report.Iterations[0] = k
+	report.HitMaxIterations = report.Residual >= opts.MinTDError
+	return report, nil
+}
+
+// sweepPartition backs up the states in a partition in place on next
+// reading the values out of the partition from cur, and returns the largest TD error.
+func (vi *ValueIterator) sweepPartition(part partition, cur, next []float64) float64 {
+	m := vi.model
+	copy(next[part.lo:part.hi], cur[part.lo:part.hi])
+	residual := 0.0
+	for stateIdx := part.lo; stateIdx < part.hi; stateIdx++ {
+		s := &m.states[stateIdx]
+		actions := vi.ToActions(s)
+		for _, a := range actions {
+			q := 0.0
+			for _, tr := range a.transitions {
+				j := tr.state.index
+				v := cur[j]
+				if part.lo <= j && j < part.hi {
+					v = next[j]
+				}
+				q += tr.p * (tr.r + vi.gamma * v)
+			}
+			vi.Q[a.index] = q
+		}
+		v, err := vi.softMax(actions)
+		if err != nil { continue }
+		residual = math.Max(residual, math.Abs(v - next[stateIdx]))
+		next[stateIdx] = v
+	}
+	return residual
+}
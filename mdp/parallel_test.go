@@ -0,0 +1,57 @@
+package mdp
+
+import (
+	"math"
+	"testing"
+)
+
+// newGridModel returns a width x height grid with unit cost moves.
+func newGridModel(width, height int) *Model {
+	ids := make([]int, 0, width * height)
+	sts := make([]StateTransition, 0)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			id := x * height + y
+			ids = append(ids, id)
+			if x + 1 < width { sts = append(sts, StateTransition{id, id + height, -1}) }
+			if x > 0 { sts = append(sts, StateTransition{id, id - height, -1}) }
+			if y + 1 < height { sts = append(sts, StateTransition{id, id + 1, -1}) }
+			if y > 0 { sts = append(sts, StateTransition{id, id - 1, -1}) }
+		}
+	}
+	gm, _ := NewModel(ids, sts)
+	return gm
+}
+
+func TestParallelValueIteration(t *testing.T) {
+	gm := newGridModel(15, 10)
+	want := NewValueIterator(gm)
+	want.SetAbsorbingState(0)
+	want.RunValueIteration()
+
+	var first []float64
+	for _, numWorkers := range []int{1, 3, 3, 8} {
+		pvi := NewValueIterator(gm)
+		pvi.SetAbsorbingState(0)
+		report := pvi.RunParallelValueIteration(numWorkers)
+		if report.HitMaxIterations {
+			t.Errorf("workers=%d: unexpected cap: %v", numWorkers, report)
+		}
+		for i := range want.V {
+			if math.Abs(pvi.V[i] - want.V[i]) > 1e-2 {
+				t.Errorf("workers=%d V@%d: got %.3f, want %.3f", numWorkers, i, pvi.V[i], want.V[i])
+			}
+		}
+		if numWorkers == 3 {
+			if first == nil {
+				first = append(first, pvi.V...)
+			} else {
+				for i := range first {
+					if pvi.V[i] != first[i] {
+						t.Errorf("workers=3 V@%d: not deterministic", i)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package mdp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"github.com/misteroda/go-rl/base"
+)
+
+const (
+	minLinearSolveError = 1e-10
+	minImprovement = 1e-12
+)
+
+// PolicyIterator represents Policy Iteration algorithm.
+// With evalSweeps = 0, each policy is evaluated exactly by solving a sparse linear system.
+// Otherwise it runs Modified Policy Iteration,
+// which evaluates each policy approximately by evalSweeps Bellman sweeps.
+type PolicyIterator struct {
+	model *Model
+	V []float64 // state values
+	Q []float64 // state-action values
+	Policy []float64
+	actionSpace
+	gamma float64 // discount factor
+	evalSweeps int
+	actionOf []*Action // current deterministic policy
+}
+
+// NewPolicyIterator constructs a PolicyIterator instance
+// with exact policy evaluation from a given Model.
+func NewPolicyIterator(model *Model) *PolicyIterator {
+	return NewModifiedPolicyIterator(model, 0)
+}
+
+// NewModifiedPolicyIterator constructs a PolicyIterator instance
+// which evaluates a policy by k sweeps from a given Model.
+func NewModifiedPolicyIterator(model *Model, k int) *PolicyIterator {
+	V := make([]float64, len(model.states))
+	pi := PolicyIterator{
+		model: model,
+		V: V,
+		Q: make([]float64, len(model.actions)),
+		Policy: make([]float64, len(model.actions)),
+		actionSpace: newActionSpace(model, V),
+		gamma: 1.0,
+		evalSweeps: k,
+		actionOf: make([]*Action, len(model.states)),
+	}
+	return &pi
+}
+
+// SetGamma sets a discount factor.
+// With gamma = 1, every state is assumed to reach an absorbing state.
+func (pi *PolicyIterator) SetGamma(gamma float64) {
+	pi.gamma = gamma
+}
+
+// Init initializes PolicyIterator instance.
+func (pi *PolicyIterator) Init() {
+	base.Vector(pi.V).Fill(0.0)
+	base.Vector(pi.Q).Fill(0.0)
+	base.Vector(pi.Policy).Fill(0.0)
+	pi.InitAbsorbingState()
+	pi.gamma = 1.0
+}
+
+// RunPolicyIteration runs Policy Iteration algorithm and updates V and Q.
+// It returns the number of policy improvement steps.
+// Use RunPolicyIterationContext to know whether it converged.
+func (pi *PolicyIterator) RunPolicyIteration() int {
+	n, _ := pi.RunPolicyIterationContext(context.Background())
+	return n
+}
+
+// RunPolicyIterationContext runs RunPolicyIteration until convergence or cancellation of ctx.
+// ctx is checked before each policy evaluation.
+// It returns ErrNotConverged if a policy cannot be evaluated exactly,
+// e.g. an improper policy with gamma = 1, or if the number of improvement steps hits the limit.
+func (pi *PolicyIterator) RunPolicyIterationContext(ctx context.Context) (int, error) {
+	pi.initPolicy()
+	for i := 0; i < maxIterations; i++ {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		if pi.evalSweeps == 0 {
+			if !pi.evaluateExactly() {
+				return i + 1, ErrNotConverged
+			}
+		} else {
+			pi.evaluateBySweeps(pi.evalSweeps)
+		}
+		changed, residual := pi.improve()
+		if !changed && (pi.evalSweeps == 0 || residual < minTDError) {
+			return i + 1, nil
+		}
+	}
+	return maxIterations, ErrNotConverged
+}
+
+// UpdatePolicy updates policy based on the current deterministic policy.
+// States without a current action, e.g. before the first run
+// or after InitAbsorbingState, take the action which is greedy in Q.
+func (pi *PolicyIterator) UpdatePolicy() {
+	m := pi.model
+	for stateIdx := range m.states {
+		s := &m.states[stateIdx]
+		actions := pi.ToActions(s)
+		if len(actions) == 0 { continue }
+		best := pi.actionOf[stateIdx]
+		if best == nil {
+			best = actions[0]
+			for _, a := range actions {
+				if pi.Q[a.index] > pi.Q[best.index] {
+					best = a
+				}
+			}
+		}
+		for _, a := range actions {
+			pi.Policy[a.index] = 0
+		}
+		pi.Policy[best.index] = 1
+	}
+}
+
+// initPolicy chooses, for each state, the action which gets closest
+// to absorbing states in the number of steps, so that the initial policy is proper.
+// Without absorbing states, the action with the highest immediate reward is chosen.
+func (pi *PolicyIterator) initPolicy() {
+	m := pi.model
+	dist := make([]float64, len(m.states))
+	queue := make([]*State, 0)
+	for i := range m.states {
+		if pi.isAbsorbing[i] {
+			queue = append(queue, &m.states[i])
+		}
+	}
+	if len(queue) > 0 {
+		base.Vector(dist).Fill(math.Inf(1))
+		for _, s := range queue {
+			dist[s.index] = 0
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, tr := range s.transitions {
+			prev := tr.action.state
+			if math.IsInf(dist[prev.index], 1) && !pi.isAbsorbing[prev.index] {
+				dist[prev.index] = dist[s.index] + 1
+				queue = append(queue, prev)
+			}
+		}
+	}
+
+	for stateIdx := range m.states {
+		s := &m.states[stateIdx]
+		pi.actionOf[stateIdx] = nil
+		bestDist, bestR := math.Inf(1), math.Inf(-1)
+		for _, a := range pi.ToActions(s) {
+			d, r := 0.0, 0.0
+			for _, tr := range a.transitions {
+				d += tr.p * dist[tr.state.index]
+				r += tr.p * tr.r
+			}
+			if pi.actionOf[stateIdx] == nil || d < bestDist || (d == bestDist && r > bestR) {
+				pi.actionOf[stateIdx] = a
+				bestDist, bestR = d, r
+			}
+		}
+	}
+}
+
+func (pi *PolicyIterator) computeQ(a *Action) float64 {
+	q := 0.0
+	for _, tr := range a.transitions {
+		q += tr.p * (tr.r + pi.gamma * pi.V[tr.state.index])
+	}
+	return q
+}
+
+// evaluateExactly solves (I - gamma P) V = r for the current policy.
+// It returns false if the solution is not found.
+func (pi *PolicyIterator) evaluateExactly() bool {
+	m := pi.model
+	n := len(m.states)
+	A := newSparseMatrix(n)
+	b := make([]float64, n)
+	for stateIdx := range m.states {
+		a := pi.actionOf[stateIdx]
+		A.appendElement(stateIdx, 1.0)
+		if a != nil {
+			for _, tr := range a.transitions {
+				A.appendElement(tr.state.index, -pi.gamma * tr.p)
+				b[stateIdx] += tr.p * tr.r
+			}
+		}
+		A.closeRow()
+	}
+	x := make([]float64, n)
+	copy(x, pi.V)
+	if A.solveBiCGSTAB(b, x, minLinearSolveError, 2 * n + 10) {
+		copy(pi.V, x)
+		return true
+	}
+	// fall back on Gauss-Seidel sweeps when the linear solver breaks down
+	for i := 0; i < maxIterations; i++ {
+		delta := pi.evaluateBySweeps(1)
+		if delta < minLinearSolveError {
+			return true
+		}
+		if math.IsNaN(delta) || math.IsInf(delta, 0) {
+			return false
+		}
+	}
+	return false
+}
+
+// evaluateBySweeps runs k in-place Bellman sweeps for the current policy.
+// It returns the maximum change of V in the last sweep.
+func (pi *PolicyIterator) evaluateBySweeps(k int) (delta float64) {
+	for i := 0; i < k; i++ {
+		delta = 0
+		for stateIdx, a := range pi.actionOf {
+			if a == nil { continue }
+			v := pi.computeQ(a)
+			delta = math.Max(delta, math.Abs(v - pi.V[stateIdx]))
+			pi.V[stateIdx] = v
+		}
+	}
+	return
+}
+
+// improve updates Q and makes the policy greedy with respect to it.
+// It returns whether the policy changed and the Bellman residual.
+func (pi *PolicyIterator) improve() (changed bool, residual float64) {
+	m := pi.model
+	for stateIdx := range m.states {
+		s := &m.states[stateIdx]
+		actions := pi.ToActions(s)
+		if len(actions) == 0 { continue }
+		current := pi.actionOf[stateIdx]
+		for _, a := range actions {
+			pi.Q[a.index] = pi.computeQ(a)
+		}
+		best := current
+		for _, a := range actions {
+			if pi.Q[a.index] > pi.Q[best.index] + minImprovement * math.Max(1.0, math.Abs(pi.Q[best.index])) {
+				best = a
+			}
+		}
+		if best != current {
+			pi.actionOf[stateIdx] = best
+			changed = true
+		}
+		residual = math.Max(residual, math.Abs(pi.Q[best.index] - pi.V[stateIdx]))
+	}
+	return
+}
+
+func (pi *PolicyIterator) String() string {
+	s := fmt.Sprintf("V: %v\n", pi.V)
+	s += fmt.Sprintf("Q: %v\n", pi.Q)
+	s += fmt.Sprintf("Policy: %v\n", pi.Policy)
+	return s
+}
@@ -0,0 +1,104 @@
+package mdp
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPolicyIteration(t *testing.T) {
+	cases := []struct {
+		k int
+		goal int
+		V []float64
+		Policy []float64
+	}{
+		{k: 0, goal: 3, V: []float64{-1, 0, -1, 0}, Policy: []float64{1, 0, 1, 1, 0}},
+		{k: 0, goal: 2, V: []float64{-2, -1, 0, -3}, Policy: []float64{1, 1, 0, 0, 1}},
+		{k: 3, goal: 3, V: []float64{-1, 0, -1, 0}, Policy: []float64{1, 0, 1, 1, 0}},
+		{k: 3, goal: 2, V: []float64{-2, -1, 0, -3}, Policy: []float64{1, 1, 0, 0, 1}},
+	}
+	for _, c := range cases {
+		pi := NewModifiedPolicyIterator(m, c.k)
+		pi.SetAbsorbingState(c.goal)
+		pi.RunPolicyIteration()
+		pi.UpdatePolicy()
+		for i, got := range pi.V {
+			if math.Abs(got - c.V[i]) > 1e-6 {
+				t.Errorf("k=%d V@%d: got %.3f, want %.3f", c.k, i, got, c.V[i])
+			}
+		}
+		for i, got := range pi.Policy {
+			if got != c.Policy[i] {
+				t.Errorf("k=%d Policy@%d: got %.3f, want %.3f", c.k, i, got, c.Policy[i])
+			}
+		}
+	}
+}
+
+func TestDiscountedPolicyIteration(t *testing.T) {
+	wantV := []float64{-10.0 / 7, -6.0 / 7, -13.0 / 7, -12.0 / 7}
+	for _, k := range []int{0, 5} {
+		pi := NewModifiedPolicyIterator(m, k)
+		pi.SetGamma(0.5)
+		pi.RunPolicyIteration()
+		for i, got := range pi.V {
+			if math.Abs(got - wantV[i]) > 1e-2 {
+				t.Errorf("k=%d V@%d: got %.3f, want %.3f", k, i, got, wantV[i])
+			}
+		}
+	}
+}
+
+func TestStochasticPolicyIteration(t *testing.T) {
+	sm, _ := NewStochasticModel(
+		[]int{0, 1, 2},
+		[]StochasticTransition{
+			{FromID: 0, Outcomes: []Outcome{{2, 1}}, Reward: -3},
+			{FromID: 0, Outcomes: []Outcome{{2, 0.5}, {1, 0.5}}, Reward: -1},
+			{FromID: 1, Outcomes: []Outcome{{2, 1}}, Reward: -1},
+		},
+	)
+	pi := NewPolicyIterator(sm)
+	pi.SetAbsorbingState(2)
+	pi.RunPolicyIteration()
+	pi.UpdatePolicy()
+	wantV := []float64{-1.5, -1, 0}
+	for i, got := range pi.V {
+		if math.Abs(got - wantV[i]) > 1e-9 {
+			t.Errorf("V@%d: got %.3f, want %.3f", i, got, wantV[i])
+		}
+	}
+	wantPolicy := []float64{0, 1, 1}
+	for i, got := range pi.Policy {
+		if got != wantPolicy[i] {
+			t.Errorf("Policy@%d: got %.3f, want %.3f", i, got, wantPolicy[i])
+		}
+	}
+}
+
+func TestImproperPolicyIteration(t *testing.T) {
+	loop, _ := NewModel([]int{0}, []StateTransition{{0, 0, -1}})
+	pi := NewPolicyIterator(loop)
+	if _, err := pi.RunPolicyIterationContext(context.Background()); err != ErrNotConverged {
+		t.Errorf("got %v, want %v", err, ErrNotConverged)
+	}
+}
+
+func TestUpdatePolicyBeforeRun(t *testing.T) {
+	pi := NewPolicyIterator(m)
+	pi.UpdatePolicy()
+	if want := []float64{1, 1, 0, 1, 1}; !reflect.DeepEqual(pi.Policy, want) {
+		t.Errorf("before run: got %v, want %v", pi.Policy, want)
+	}
+	// the state which was absorbing has no current action
+	pi.Init()
+	pi.SetAbsorbingState(3)
+	pi.RunPolicyIteration()
+	pi.InitAbsorbingState()
+	pi.UpdatePolicy()
+	if want := []float64{1, 0, 1, 1, 1}; !reflect.DeepEqual(pi.Policy, want) {
+		t.Errorf("after InitAbsorbingState: got %v, want %v", pi.Policy, want)
+	}
+}
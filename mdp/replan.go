@@ -0,0 +1,38 @@
+package mdp
+
+import (
+	"context"
+)
+
+// Replan repairs V and Q incrementally after the rewards of given actions
+// are updated, e.g. by Model.UpdateActionReward.
+// Instead of sweeping all states, the priority queue is seeded only with
+// the states taking the actions, and changes propagate to their predecessors.
+// V must be a converged solution for the rewards before the update.
+// The returned report has a single stage and no residual.
+func (vi *ValueIterator) Replan(actionIndices []int) (ConvergenceReport, error) {
+	return vi.ReplanContext(context.Background(), actionIndices)
+}
+
+// ReplanContext runs Replan until convergence or cancellation of ctx.
+func (vi *ValueIterator) ReplanContext(ctx context.Context, actionIndices []int) (ConvergenceReport, error) {
+	m := vi.model
+	opts := &vi.opts
+	report := ConvergenceReport{Iterations: make([]int, 1)}
+	pq := NewPriorityQueue(len(m.states))
+	for _, i := range actionIndices {
+		if i < 0 || i >= len(m.actions) {
+			return report, &UnknownActionError{i}
+		}
+		s := m.actions[i].state
+		if s == nil { continue }
+		td := vi.bellmanBackup(s)
+		if td > opts.MinTDError && opts.hasRoom(pq) {
+			pq.Push(s.index, td)
+		}
+	}
+	j, _, err := vi.sweep(ctx, pq, opts.MinTDError)
+	report.Iterations[0] = j
+	report.HitMaxIterations = pq.Size() > 0
+	return report, err
+}
@@ -0,0 +1,45 @@
+package mdp
+
+import (
+	"testing"
+)
+
+func TestReplan(t *testing.T) {
+	rm, _ := NewModel(
+		[]int{0, 1, 2, 3},
+		[]StateTransition{{0, 1, -1}, {1, 2, -1}, {1, 3, 0}, {2, 3, -1}, {3, 0, -1}},
+	)
+	rvi := NewValueIterator(rm)
+	run(rvi, 2)
+
+	// close the road 1 -> 2
+	changed := []int{1}
+	if err := rm.UpdateActionReward(changed, []float64{-10}); err != nil {
+		t.Fatalf("UpdateActionReward: %v", err)
+	}
+	if _, err := rvi.Replan(changed); err != nil {
+		t.Fatalf("Replan: %v", err)
+	}
+	rvi.UpdatePolicy()
+
+	want := NewValueIterator(rm)
+	run(want, 2)
+	for i := range want.V {
+		if rvi.V[i] != want.V[i] {
+			t.Errorf("V@%d: got %.3f, want %.3f", i, rvi.V[i], want.V[i])
+		}
+	}
+	for i := range want.Policy {
+		if rvi.Policy[i] != want.Policy[i] {
+			t.Errorf("Policy@%d: got %.3f, want %.3f", i, rvi.Policy[i], want.Policy[i])
+		}
+	}
+
+	if err := rm.UpdateActionReward([]int{7}, []float64{0}); err == nil {
+		t.Errorf("UpdateActionReward: want *UnknownActionError")
+	}
+	dm, _ := NewModel([]int{0, 1}, []StateTransition{{0, 1, -1}, {1, 5, -1}})
+	if _, ok := dm.UpdateActionReward([]int{1}, []float64{0}).(*UnknownActionError); !ok {
+		t.Errorf("UpdateActionReward of a dropped action: want *UnknownActionError")
+	}
+}
@@ -0,0 +1,367 @@
+package mdp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"github.com/misteroda/go-rl/base"
+)
+
+// ReturnDistribution is an empirical distribution of returns sampled from a start state.
+// Episodes which do not reach an absorbing state, i.e. truncated at the limit of steps
+// or stopped at a dead end, are counted in Truncated and their returns are left out.
+type ReturnDistribution struct {
+	Returns []float64 // sorted in ascending order
+	Truncated int // number of episodes left out
+}
+
+// Mean returns the expected return.
+func (d *ReturnDistribution) Mean() float64 {
+	if len(d.Returns) == 0 {
+		return 0
+	}
+	return base.Vector(d.Returns).Sum() / float64(len(d.Returns))
+}
+
+// VaR returns the value at risk at level alpha, i.e. the alpha-quantile of returns.
+func (d *ReturnDistribution) VaR(alpha float64) float64 {
+	if len(d.Returns) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(alpha * float64(len(d.Returns)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return d.Returns[i]
+}
+
+// CVaR returns the conditional value at risk at level alpha,
+// i.e. the mean of the worst alpha fraction of returns.
+func (d *ReturnDistribution) CVaR(alpha float64) float64 {
+	n := int(math.Ceil(alpha * float64(len(d.Returns))))
+	if n < 1 {
+		n = 1
+	}
+	if n > len(d.Returns) {
+		n = len(d.Returns)
+	}
+	if n == 0 {
+		return 0
+	}
+	return base.Vector(d.Returns[:n]).Sum() / float64(n)
+}
+
+// sampleReturns samples returns of episodes from startState until an absorbing state,
+// choosing actions by next which also receives the return so far.
+func sampleReturns(start *State, isAbsorbing []bool, rnd *rand.Rand, numSamples, maxSteps int, next func(s *State, g float64) (*Action, error)) (*ReturnDistribution, error) {
+	d := &ReturnDistribution{Returns: make([]float64, 0, numSamples)}
+	for i := 0; i < numSamples; i++ {
+		s := start
+		g := 0.0
+		for k := 0; k < maxSteps && !isAbsorbing[s.index]; k++ {
+			a, err := next(s, g)
+			if err == ErrNoAction {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			tr := transitionAt(a, rnd.Float64())
+			g += tr.r
+			s = tr.state
+		}
+		if !isAbsorbing[s.index] {
+			d.Truncated++
+			continue
+		}
+		d.Returns = append(d.Returns, g)
+	}
+	sort.Float64s(d.Returns)
+	return d, nil
+}
+
+// ExponentialUtilityIterator represents Value Iteration for the exponential utility of returns.
+// V is the certainty equivalent (1/beta) log E[exp(beta G)] of the return G.
+// A negative beta is risk-averse, a positive beta is risk-seeking,
+// and beta = 0 is the expected return.
+// Returns are not discounted, so every policy is assumed to reach an absorbing state.
+type ExponentialUtilityIterator struct {
+	model *Model
+	V []float64 // certainty equivalents of states
+	Q []float64 // certainty equivalents of state-action pairs
+	Policy []float64
+	greedyPolicy
+	beta float64 // risk sensitivity
+	convergence
+	rand *rand.Rand
+}
+
+// NewExponentialUtilityIterator constructs an ExponentialUtilityIterator instance
+// with risk sensitivity beta from a given Model.
+func NewExponentialUtilityIterator(model *Model, beta float64) *ExponentialUtilityIterator {
+	V := make([]float64, len(model.states))
+	Q := make([]float64, len(model.actions))
+	policy := make([]float64, len(model.actions))
+	return &ExponentialUtilityIterator{
+		model: model,
+		V: V,
+		Q: Q,
+		Policy: policy,
+		greedyPolicy: newGreedyPolicy(model, V, Q, policy),
+		beta: beta,
+		convergence: convergence{DefaultOptions()},
+		rand: rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+// SetRand sets the source of randomness of ReturnDistribution, e.g. seeded for reproducibility.
+func (eu *ExponentialUtilityIterator) SetRand(r *rand.Rand) {
+	eu.rand = r
+}
+
+// certaintyEquivalent computes (1/beta) log E[exp(beta (r + V(s')))] of an action.
+func (eu *ExponentialUtilityIterator) certaintyEquivalent(a *Action) float64 {
+	if eu.beta == 0 {
+		q := 0.0
+		for _, tr := range a.transitions {
+			q += tr.p * (tr.r + eu.V[tr.state.index])
+		}
+		return q
+	}
+	maxX := math.Inf(-1)
+	for _, tr := range a.transitions {
+		maxX = math.Max(maxX, eu.beta * (tr.r + eu.V[tr.state.index]))
+	}
+	sum := 0.0
+	for _, tr := range a.transitions {
+		sum += tr.p * math.Exp(eu.beta * (tr.r + eu.V[tr.state.index]) - maxX)
+	}
+	return (math.Log(sum) + maxX) / eu.beta
+}
+
+// RunValueIteration runs Value Iteration on certainty equivalents and updates V and Q.
+func (eu *ExponentialUtilityIterator) RunValueIteration() ConvergenceReport {
+	report, _ := eu.RunValueIterationContext(context.Background())
+	return report
+}
+
+// RunValueIterationContext runs RunValueIteration until convergence or cancellation of ctx.
+// Iterations of the report holds the number of sweeps.
+func (eu *ExponentialUtilityIterator) RunValueIterationContext(ctx context.Context) (ConvergenceReport, error) {
+	m := eu.model
+	report := ConvergenceReport{Iterations: make([]int, 1)}
+	var k int
+	for k = 0; k < eu.opts.MaxIterations; k++ {
+		if ctx.Err() != nil {
+			report.Iterations[0] = k
+			return report, ctx.Err()
+		}
+		report.Residual = 0
+		for stateIdx := range m.states {
+			actions := eu.ToActions(&m.states[stateIdx])
+			if len(actions) == 0 { continue }
+			v := math.Inf(-1)
+			for _, a := range actions {
+				eu.Q[a.index] = eu.certaintyEquivalent(a)
+				v = math.Max(v, eu.Q[a.index])
+			}
+			report.Residual = math.Max(report.Residual, math.Abs(v - eu.V[stateIdx]))
+			eu.V[stateIdx] = v
+		}
+		if report.Residual < eu.opts.MinTDError {
+			k++
+			break
+		}
+	}
+	report.Iterations[0] = k
+	report.HitMaxIterations = report.Residual >= eu.opts.MinTDError
+	return report, nil
+}
+
+// ReturnDistribution samples numSamples episodes of the current policy from a start state
+// over at most maxSteps steps each.
+func (eu *ExponentialUtilityIterator) ReturnDistribution(startID, numSamples, maxSteps int) (*ReturnDistribution, error) {
+	start, ok := eu.model.StateOf[startID]
+	if !ok { return nil, &UnknownStateError{startID} }
+	vi := newPolicyVisitor(eu.model, eu.Policy, eu.isAbsorbing, 1.0)
+	return sampleReturns(start, eu.isAbsorbing, eu.rand, numSamples, maxSteps, func(s *State, g float64) (*Action, error) {
+		return vi.actionAt(eu.ToActions(s), eu.rand.Float64())
+	})
+}
+
+func (eu *ExponentialUtilityIterator) String() string {
+	s := fmt.Sprintf("V: %v\n", eu.V)
+	s += fmt.Sprintf("Q: %v\n", eu.Q)
+	s += fmt.Sprintf("Policy: %v\n", eu.Policy)
+	return s
+}
+
+// CVaRSolver optimizes the conditional value at risk of undiscounted returns
+// by Value Iteration on states augmented with a return threshold y.
+// W[s][j] is the minimum of E[(y_j - G)^+] over policies for the return G from state s,
+// and CVaR at level alpha is the maximum of y - W(s, y) / alpha over y.
+// The optimal policy depends on the return so far, so it is given by Action.
+// Levels should cover the range of returns, e.g. from the worst return to 0 for costs.
+type CVaRSolver struct {
+	model *Model
+	W [][]float64
+	levels []float64 // thresholds y_j in ascending order
+	alpha float64 // confidence level
+	actionSpace
+	convergence
+	rand *rand.Rand
+}
+
+// NewCVaRSolver constructs a CVaRSolver instance at confidence level alpha in (0, 1]
+// from a given Model, with numLevels thresholds evenly spaced in [minLevel, maxLevel].
+func NewCVaRSolver(model *Model, alpha, minLevel, maxLevel float64, numLevels int) *CVaRSolver {
+	if numLevels < 2 {
+		numLevels = 2
+	}
+	levels := make([]float64, numLevels)
+	for j := range levels {
+		levels[j] = minLevel + (maxLevel - minLevel) * float64(j) / float64(numLevels - 1)
+	}
+	cs := CVaRSolver{
+		model: model,
+		W: make([][]float64, len(model.states)),
+		levels: levels,
+		alpha: alpha,
+		actionSpace: newActionSpace(model, nil),
+		convergence: convergence{DefaultOptions()},
+		rand: rand.New(rand.NewSource(rand.Int63())),
+	}
+	for i := range cs.W {
+		cs.W[i] = make([]float64, numLevels)
+	}
+	return &cs
+}
+
+// SetRand sets the source of randomness of ReturnDistribution, e.g. seeded for reproducibility.
+func (cs *CVaRSolver) SetRand(r *rand.Rand) {
+	cs.rand = r
+}
+
+// interpolate returns W(s, y) interpolated linearly between levels.
+// Below the levels it is clamped, and above them it grows with slope 1
+// as every return is assumed to be below the highest level.
+func (cs *CVaRSolver) interpolate(stateIdx int, y float64) float64 {
+	w := cs.W[stateIdx]
+	levels := cs.levels
+	n := len(levels)
+	if y <= levels[0] {
+		return w[0]
+	}
+	if y >= levels[n - 1] {
+		return w[n - 1] + y - levels[n - 1]
+	}
+	j := sort.SearchFloat64s(levels, y)
+	t := (y - levels[j - 1]) / (levels[j] - levels[j - 1])
+	return (1 - t) * w[j - 1] + t * w[j]
+}
+
+// shortfall returns E[(y - G)^+] of taking an action at threshold y.
+func (cs *CVaRSolver) shortfall(a *Action, y float64) float64 {
+	q := 0.0
+	for _, tr := range a.transitions {
+		q += tr.p * cs.interpolate(tr.state.index, y - tr.r)
+	}
+	return q
+}
+
+// Solve runs Value Iteration on the augmented states and updates W.
+func (cs *CVaRSolver) Solve() ConvergenceReport {
+	report, _ := cs.SolveContext(context.Background())
+	return report
+}
+
+// SolveContext runs Solve until convergence or cancellation of ctx.
+// Iterations of the report holds the number of sweeps.
+func (cs *CVaRSolver) SolveContext(ctx context.Context) (ConvergenceReport, error) {
+	m := cs.model
+	for i := range cs.W {
+		for j, y := range cs.levels {
+			cs.W[i][j] = math.Max(y, 0)
+		}
+	}
+	report := ConvergenceReport{Iterations: make([]int, 1)}
+	var k int
+	for k = 0; k < cs.opts.MaxIterations; k++ {
+		if ctx.Err() != nil {
+			report.Iterations[0] = k
+			return report, ctx.Err()
+		}
+		report.Residual = 0
+		for stateIdx := range m.states {
+			actions := cs.ToActions(&m.states[stateIdx])
+			if len(actions) == 0 { continue }
+			for j, y := range cs.levels {
+				w := math.Inf(1)
+				for _, a := range actions {
+					w = math.Min(w, cs.shortfall(a, y))
+				}
+				report.Residual = math.Max(report.Residual, math.Abs(w - cs.W[stateIdx][j]))
+				cs.W[stateIdx][j] = w
+			}
+		}
+		if report.Residual < cs.opts.MinTDError {
+			k++
+			break
+		}
+	}
+	report.Iterations[0] = k
+	report.HitMaxIterations = report.Residual >= cs.opts.MinTDError
+	return report, nil
+}
+
+// CVaR returns the optimal conditional value at risk of returns from a start state
+// and the threshold y from which Action should start.
+func (cs *CVaRSolver) CVaR(startID int) (float64, float64, error) {
+	start, ok := cs.model.StateOf[startID]
+	if !ok { return 0, 0, &UnknownStateError{startID} }
+	best, bestY := math.Inf(-1), cs.levels[0]
+	for j, y := range cs.levels {
+		if v := y - cs.W[start.index][j] / cs.alpha; v > best {
+			best, bestY = v, y
+		}
+	}
+	return best, bestY, nil
+}
+
+// Action returns the optimal action at a state with threshold y,
+// which is the starting threshold minus the return so far.
+func (cs *CVaRSolver) Action(stateID int, y float64) (*Action, error) {
+	s, ok := cs.model.StateOf[stateID]
+	if !ok { return nil, &UnknownStateError{stateID} }
+	return cs.action(s, y)
+}
+
+func (cs *CVaRSolver) action(s *State, y float64) (*Action, error) {
+	actions := cs.ToActions(s)
+	if len(actions) == 0 {
+		return nil, ErrNoAction
+	}
+	best := actions[0]
+	bestW := cs.shortfall(best, y)
+	for _, a := range actions[1:] {
+		if w := cs.shortfall(a, y); w < bestW {
+			best, bestW = a, w
+		}
+	}
+	return best, nil
+}
+
+// ReturnDistribution samples numSamples episodes of the CVaR optimal policy from a start state
+// over at most maxSteps steps each.
+func (cs *CVaRSolver) ReturnDistribution(startID, numSamples, maxSteps int) (*ReturnDistribution, error) {
+	_, y, err := cs.CVaR(startID)
+	if err != nil {
+		return nil, err
+	}
+	start := cs.model.StateOf[startID]
+	return sampleReturns(start, cs.isAbsorbing, cs.rand, numSamples, maxSteps, func(s *State, g float64) (*Action, error) {
+		return cs.action(s, y - g)
+	})
+}
@@ -0,0 +1,104 @@
+package mdp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// newRiskModel returns a model where 0 reaches the goal 3 safely at cost 10,
+// or riskily at cost 1 plus 50 with probability 0.1 through 2.
+func newRiskModel() *Model {
+	rm, _ := NewStochasticModel([]int{0, 1, 2, 3}, []StochasticTransition{
+		{FromID: 0, Outcomes: []Outcome{{3, 1}}, Reward: -10},
+		{FromID: 0, Outcomes: []Outcome{{1, 0.9}, {2, 0.1}}, Reward: -1},
+		{FromID: 1, Outcomes: []Outcome{{3, 1}}, Reward: 0},
+		{FromID: 2, Outcomes: []Outcome{{3, 1}}, Reward: -50},
+	})
+	return rm
+}
+
+func TestExponentialUtility(t *testing.T) {
+	rm := newRiskModel()
+	for _, tc := range []struct {
+		beta float64
+		wantAction int
+	}{
+		{0, 1},
+		{-0.01, 1},
+		{-1, 0},
+	} {
+		eu := NewExponentialUtilityIterator(rm, tc.beta)
+		eu.SetAbsorbingState(3)
+		if report := eu.RunValueIteration(); report.HitMaxIterations {
+			t.Errorf("beta=%v: unexpected cap: %v", tc.beta, report)
+		}
+		eu.UpdatePolicy()
+		if eu.Policy[tc.wantAction] != 1 {
+			t.Errorf("beta=%v policy: got %v, want action %d", tc.beta, eu.Policy, tc.wantAction)
+		}
+		if tc.beta == 0 && math.Abs(eu.V[0] + 6) > 1e-9 {
+			t.Errorf("beta=0 V@0: got %.3f, want -6", eu.V[0])
+		}
+	}
+
+	eu := NewExponentialUtilityIterator(rm, -0.01)
+	eu.SetRand(rand.New(rand.NewSource(1)))
+	eu.SetAbsorbingState(3)
+	eu.RunValueIteration()
+	eu.UpdatePolicy()
+	d, err := eu.ReturnDistribution(0, 2000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Truncated != 0 || len(d.Returns) != 2000 {
+		t.Errorf("got %d returns and %d truncated, want 2000 and 0", len(d.Returns), d.Truncated)
+	}
+	if d.Returns[0] != -51 || d.Returns[len(d.Returns) - 1] != -1 {
+		t.Errorf("returns: got range [%.1f, %.1f], want [-51, -1]", d.Returns[0], d.Returns[len(d.Returns) - 1])
+	}
+	if math.Abs(d.Mean() + 6) > 2 {
+		t.Errorf("mean return: got %.3f, want about -6", d.Mean())
+	}
+	// the risky action takes two steps to the goal
+	if d, _ = eu.ReturnDistribution(0, 10, 1); d.Truncated != 10 || len(d.Returns) != 0 {
+		t.Errorf("got %d returns and %d truncated, want 0 and 10", len(d.Returns), d.Truncated)
+	}
+}
+
+func TestCVaR(t *testing.T) {
+	rm := newRiskModel()
+	for _, tc := range []struct {
+		alpha float64
+		want float64
+		wantAction int
+	}{
+		{0.05, -10, 0},
+		{1, -6, 1},
+	} {
+		cs := NewCVaRSolver(rm, tc.alpha, -60, 0, 61)
+		cs.SetRand(rand.New(rand.NewSource(1)))
+		cs.SetAbsorbingState(3)
+		if report := cs.Solve(); report.HitMaxIterations {
+			t.Errorf("alpha=%v: unexpected cap: %v", tc.alpha, report)
+		}
+		cvar, y, err := cs.CVaR(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(cvar - tc.want) > 1e-6 {
+			t.Errorf("alpha=%v CVaR: got %.3f, want %.3f", tc.alpha, cvar, tc.want)
+		}
+		a, err := cs.Action(0, y)
+		if err != nil || a.Index() != tc.wantAction {
+			t.Errorf("alpha=%v action: got %v, %v, want %d", tc.alpha, a, err, tc.wantAction)
+		}
+		d, err := cs.ReturnDistribution(0, 1000, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.wantAction == 0 && d.CVaR(tc.alpha) != -10 {
+			t.Errorf("alpha=%v sampled CVaR: got %.3f, want -10", tc.alpha, d.CVaR(tc.alpha))
+		}
+	}
+}
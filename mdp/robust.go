@@ -0,0 +1,243 @@
+package mdp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// UncertaintySet is a set of outcome probabilities of an action around the nominal ones.
+type UncertaintySet interface {
+	// WorstCase returns the minimum of the expectation of values over probabilities in the set.
+	// nominal and values are indexed by the outcomes of the action.
+	WorstCase(nominal, values []float64) float64
+}
+
+// sizedSet is implemented by an UncertaintySet defined on a fixed number of outcomes.
+type sizedSet interface {
+	NumOutcomes() int
+}
+
+// L1Ball is the set of probabilities within L1 distance Radius from the nominal ones
+// on the outcomes of the action.
+type L1Ball struct {
+	Radius float64
+}
+
+// WorstCase implements UncertaintySet. It moves up to Radius / 2 of probability
+// from the outcomes of the highest values to the outcome of the lowest value.
+func (b L1Ball) WorstCase(nominal, values []float64) float64 {
+	order := sortedByValue(values)
+	p := append([]float64(nil), nominal...)
+	lowest := order[0]
+	budget := math.Min(b.Radius / 2, 1 - p[lowest])
+	p[lowest] += budget
+	for k := len(order) - 1; k > 0 && budget > 0; k-- {
+		i := order[k]
+		moved := math.Min(budget, p[i])
+		p[i] -= moved
+		budget -= moved
+	}
+	return dot(p, values)
+}
+
+// Interval is the set of probabilities within bounds on each outcome of the action.
+// Lower and Upper are indexed by the outcomes, with 0 <= Lower <= Upper <= 1,
+// and Lower must sum to at most 1 and Upper to at least 1.
+// It applies only to actions with as many outcomes.
+type Interval struct {
+	Lower, Upper []float64
+}
+
+// NewInterval returns an Interval of nominal probabilities widened by delta and clipped to [0, 1].
+func NewInterval(nominal []float64, delta float64) Interval {
+	iv := Interval{Lower: make([]float64, len(nominal)), Upper: make([]float64, len(nominal))}
+	for i, p := range nominal {
+		iv.Lower[i] = math.Max(0, p - delta)
+		iv.Upper[i] = math.Min(1, p + delta)
+	}
+	return iv
+}
+
+// NumOutcomes returns the number of outcomes of the Interval.
+func (iv Interval) NumOutcomes() int {
+	return len(iv.Lower)
+}
+
+// contains reports whether the bounds contain a probability distribution.
+func (iv Interval) contains() bool {
+	lower, upper := 0.0, 0.0
+	for i := range iv.Lower {
+		if !(0 <= iv.Lower[i] && iv.Lower[i] <= iv.Upper[i] && iv.Upper[i] <= 1) {
+			return false
+		}
+		lower += iv.Lower[i]
+		upper += iv.Upper[i]
+	}
+	return lower <= 1 + probabilityTolerance && upper >= 1 - probabilityTolerance
+}
+
+// WorstCase implements UncertaintySet. It starts from the lower bounds
+// and assigns the remaining probability to the outcomes of the lowest values first.
+// Lower and Upper must have the length of values.
+func (iv Interval) WorstCase(nominal, values []float64) float64 {
+	p := append([]float64(nil), iv.Lower...)
+	budget := 1.0
+	for _, l := range p {
+		budget -= l
+	}
+	for _, i := range sortedByValue(values) {
+		if budget <= 0 { break }
+		added := math.Min(budget, iv.Upper[i] - p[i])
+		p[i] += added
+		budget -= added
+	}
+	return dot(p, values)
+}
+
+// sortedByValue returns the indices of values in ascending order of values.
+func sortedByValue(values []float64) []int {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+	return order
+}
+
+// RobustValueIterator represents robust Value Iteration, where nature chooses
+// the worst outcome probabilities of each action within its UncertaintySet.
+// V is the worst-case value of the Policy.
+type RobustValueIterator struct {
+	model *Model
+	V []float64 // worst-case state values
+	Q []float64 // worst-case state-action values
+	Policy []float64
+	greedyPolicy
+	gamma float64 // discount factor
+	sets []UncertaintySet // per action, nominal probabilities if nil
+	convergence
+}
+
+// NewRobustValueIterator constructs a RobustValueIterator instance from a given Model
+// with an UncertaintySet shared by every action. With nil, actions are nominal until SetUncertainty.
+// It returns *DimensionMismatchError if the set does not fit the outcomes of an action, as SetUncertainty does.
+func NewRobustValueIterator(model *Model, set UncertaintySet) (*RobustValueIterator, error) {
+	V := make([]float64, len(model.states))
+	Q := make([]float64, len(model.actions))
+	policy := make([]float64, len(model.actions))
+	rvi := RobustValueIterator{
+		model: model,
+		V: V,
+		Q: Q,
+		Policy: policy,
+		greedyPolicy: newGreedyPolicy(model, V, Q, policy),
+		gamma: 1.0,
+		sets: make([]UncertaintySet, len(model.actions)),
+		convergence: convergence{DefaultOptions()},
+	}
+	for i := range rvi.sets {
+		if err := rvi.SetUncertainty(i, set); err != nil {
+			return nil, err
+		}
+	}
+	return &rvi, nil
+}
+
+// SetUncertainty sets the UncertaintySet of an action.
+// It returns *DimensionMismatchError if the set is defined on a different number of outcomes,
+// e.g. an Interval with bounds of another length,
+// and *InvalidIntervalError if the bounds of an Interval contain no probability distribution.
+func (rvi *RobustValueIterator) SetUncertainty(actionIndex int, set UncertaintySet) error {
+	if actionIndex < 0 || actionIndex >= len(rvi.sets) {
+		return &UnknownActionError{actionIndex}
+	}
+	if iv, ok := set.(Interval); ok {
+		if len(iv.Upper) != len(iv.Lower) {
+			return &DimensionMismatchError{Got: len(iv.Upper), Want: len(iv.Lower)}
+		}
+		if !iv.contains() {
+			return &InvalidIntervalError{iv.Lower, iv.Upper}
+		}
+	}
+	if ss, ok := set.(sizedSet); ok {
+		a := &rvi.model.actions[actionIndex]
+		if a.state != nil && ss.NumOutcomes() != len(a.transitions) {
+			return &DimensionMismatchError{Got: ss.NumOutcomes(), Want: len(a.transitions)}
+		}
+	}
+	rvi.sets[actionIndex] = set
+	return nil
+}
+
+// SetGamma sets a discount factor.
+// With gamma = 1, every policy is assumed to reach an absorbing state under the worst case.
+func (rvi *RobustValueIterator) SetGamma(gamma float64) {
+	rvi.gamma = gamma
+}
+
+// worstCaseQ returns the worst-case state-action value of an action.
+func (rvi *RobustValueIterator) worstCaseQ(a *Action, nominal, values []float64) float64 {
+	nominal, values = nominal[:0], values[:0]
+	for _, tr := range a.transitions {
+		nominal = append(nominal, tr.p)
+		values = append(values, tr.r + rvi.gamma * rvi.V[tr.state.index])
+	}
+	set := rvi.sets[a.index]
+	if set == nil {
+		return dot(nominal, values)
+	}
+	return set.WorstCase(nominal, values)
+}
+
+// RunValueIteration runs robust Value Iteration and updates V and Q.
+func (rvi *RobustValueIterator) RunValueIteration() ConvergenceReport {
+	report, _ := rvi.RunValueIterationContext(context.Background())
+	return report
+}
+
+// RunValueIterationContext runs RunValueIteration until convergence or cancellation of ctx.
+// Iterations of the report holds the number of sweeps.
+func (rvi *RobustValueIterator) RunValueIterationContext(ctx context.Context) (ConvergenceReport, error) {
+	m := rvi.model
+	report := ConvergenceReport{Iterations: make([]int, 1)}
+	var nominal, values []float64
+	var k int
+	for k = 0; k < rvi.opts.MaxIterations; k++ {
+		if ctx.Err() != nil {
+			report.Iterations[0] = k
+			return report, ctx.Err()
+		}
+		report.Residual = 0
+		for stateIdx := range m.states {
+			actions := rvi.ToActions(&m.states[stateIdx])
+			if len(actions) == 0 { continue }
+			v := math.Inf(-1)
+			for _, a := range actions {
+				if cap(nominal) < len(a.transitions) {
+					nominal = make([]float64, len(a.transitions))
+					values = make([]float64, len(a.transitions))
+				}
+				rvi.Q[a.index] = rvi.worstCaseQ(a, nominal, values)
+				v = math.Max(v, rvi.Q[a.index])
+			}
+			report.Residual = math.Max(report.Residual, math.Abs(v - rvi.V[stateIdx]))
+			rvi.V[stateIdx] = v
+		}
+		if report.Residual < rvi.opts.MinTDError {
+			k++
+			break
+		}
+	}
+	report.Iterations[0] = k
+	report.HitMaxIterations = report.Residual >= rvi.opts.MinTDError
+	return report, nil
+}
+
+func (rvi *RobustValueIterator) String() string {
+	s := fmt.Sprintf("V: %v\n", rvi.V)
+	s += fmt.Sprintf("Q: %v\n", rvi.Q)
+	s += fmt.Sprintf("Policy: %v\n", rvi.Policy)
+	return s
+}
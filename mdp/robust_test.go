@@ -0,0 +1,68 @@
+package mdp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRobustValueIteration(t *testing.T) {
+	rm := newRiskModel()
+	for _, tc := range []struct {
+		name string
+		set UncertaintySet
+		wantV float64
+		wantAction int
+	}{
+		{"nominal", nil, -6, 1},
+		{"L1 0.1", L1Ball{0.1}, -8.5, 1},
+		{"L1 0.2", L1Ball{0.2}, -10, 0},
+		{"interval 0.1", NewInterval([]float64{0.9, 0.1}, 0.1), -10, 0},
+	} {
+		rvi, err := NewRobustValueIterator(rm, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rvi.SetUncertainty(1, tc.set); err != nil {
+			t.Fatal(err)
+		}
+		rvi.SetAbsorbingState(3)
+		if report := rvi.RunValueIteration(); report.HitMaxIterations {
+			t.Errorf("%s: unexpected cap: %v", tc.name, report)
+		}
+		rvi.UpdatePolicy()
+		if math.Abs(rvi.V[0] - tc.wantV) > 1e-9 {
+			t.Errorf("%s V@0: got %.3f, want %.3f", tc.name, rvi.V[0], tc.wantV)
+		}
+		if rvi.Policy[tc.wantAction] != 1 {
+			t.Errorf("%s policy: got %v, want action %d", tc.name, rvi.Policy, tc.wantAction)
+		}
+	}
+	if math.Abs(L1Ball{0.2}.WorstCase([]float64{0.9, 0.1}, []float64{-1, -51}) + 11) > 1e-9 {
+		t.Errorf("L1Ball worst case is not -11")
+	}
+
+	rvi, err := NewRobustValueIterator(rm, L1Ball{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rvi.SetUncertainty(4, nil); err == nil {
+		t.Errorf("got nil, want *UnknownActionError")
+	}
+	// an Interval of two outcomes does not fit actions with a single outcome
+	if _, err := NewRobustValueIterator(rm, NewInterval([]float64{0.9, 0.1}, 0.1)); err == nil {
+		t.Errorf("got nil, want *DimensionMismatchError")
+	}
+	if err := rvi.SetUncertainty(0, Interval{Lower: []float64{0.5}, Upper: []float64{1, 1}}); err == nil {
+		t.Errorf("got nil, want *DimensionMismatchError")
+	}
+	for _, iv := range []Interval{
+		{Lower: []float64{0.6, 0.5}, Upper: []float64{1, 1}},
+		{Lower: []float64{0, 0}, Upper: []float64{0.4, 0.5}},
+		{Lower: []float64{0.5, 0}, Upper: []float64{0.4, 1}},
+		{Lower: []float64{-0.1, 0}, Upper: []float64{1, 1.1}},
+	} {
+		if _, ok := rvi.SetUncertainty(1, iv).(*InvalidIntervalError); !ok {
+			t.Errorf("%v: want *InvalidIntervalError", iv)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package mdp
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	simplexEps = 1e-9
+)
+
+var (
+	// ErrInfeasible is returned when a linear program has no feasible solution.
+	ErrInfeasible = errors.New("mdp: infeasible linear program")
+	// ErrUnbounded is returned when the objective of a linear program is unbounded.
+	ErrUnbounded = errors.New("mdp: unbounded linear program")
+)
+
+// ConstraintSense represents the relation of a linear constraint.
+type ConstraintSense int
+
+const (
+	LessEqual ConstraintSense = iota
+	Equal
+	GreaterEqual
+)
+
+// linearProgram is a problem to maximize c x subject to linear constraints and x >= 0,
+// solved by the two-phase simplex method on a dense tableau.
+// It is meant for small and medium models, as the tableau holds
+// (number of constraints) x (number of variables) elements.
+type linearProgram struct {
+	c []float64
+	rows [][]float64
+	senses []ConstraintSense
+	b []float64
+}
+
+func newLinearProgram(c []float64) *linearProgram {
+	return &linearProgram{c: c}
+}
+
+// addConstraint adds a constraint coef x (sense) b. coef is not copied.
+func (lp *linearProgram) addConstraint(coef []float64, sense ConstraintSense, b float64) {
+	lp.rows = append(lp.rows, coef)
+	lp.senses = append(lp.senses, sense)
+	lp.b = append(lp.b, b)
+}
+
+// tableau is a simplex tableau whose last column is the right hand side.
+type tableau struct {
+	t [][]float64
+	basis []int
+	bland bool // whether Bland's rule is used to avoid cycling
+}
+
+// pivot makes column col basic in row row.
+func (tb *tableau) pivot(row, col int) {
+	pr := tb.t[row]
+	p := pr[col]
+	for j := range pr {
+		pr[j] /= p
+	}
+	for i, r := range tb.t {
+		if i == row || r[col] == 0 { continue }
+		f := r[col]
+		for j := range r {
+			r[j] -= f * pr[j]
+		}
+		r[col] = 0
+	}
+	tb.basis[row] = col
+}
+
+// optimize maximizes cost x over the columns for which allowed is true.
+func (tb *tableau) optimize(cost []float64, allowed func(j int) bool) error {
+	rhs := len(tb.t[0]) - 1
+	reduced := make([]float64, rhs)
+	for {
+		// reduced costs cost_j - cost_B B^-1 A_j
+		copy(reduced, cost)
+		for i, r := range tb.t {
+			cb := cost[tb.basis[i]]
+			if cb == 0 { continue }
+			for j := 0; j < rhs; j++ {
+				reduced[j] -= cb * r[j]
+			}
+		}
+		col := -1
+		for j := 0; j < rhs; j++ {
+			if !allowed(j) || reduced[j] <= simplexEps { continue }
+			if col < 0 || (!tb.bland && reduced[j] > reduced[col]) {
+				col = j
+			}
+			if tb.bland { break }
+		}
+		if col < 0 {
+			return nil
+		}
+		row := -1
+		minRatio := math.Inf(1)
+		for i, r := range tb.t {
+			if r[col] <= simplexEps { continue }
+			ratio := r[rhs] / r[col]
+			if ratio < minRatio - simplexEps || (ratio < minRatio + simplexEps && row >= 0 && tb.basis[i] < tb.basis[row]) {
+				row = i
+				minRatio = ratio
+			}
+		}
+		if row < 0 {
+			return ErrUnbounded
+		}
+		if minRatio < simplexEps {
+			// a degenerate pivot may cycle
+			tb.bland = true
+		}
+		tb.pivot(row, col)
+	}
+}
+
+// maximize solves the linear program and returns an optimal x
+// and the dual values, i.e. the shadow prices, of the constraints.
+func (lp *linearProgram) maximize() (x []float64, dual []float64, err error) {
+	n := len(lp.c)
+	m := len(lp.rows)
+	// columns: variables, then a slack or surplus and an artificial per row
+	numCols := n + 2 * m
+	rhs := numCols
+	tb := &tableau{t: make([][]float64, m), basis: make([]int, m)}
+	flipped := make([]bool, m)
+	identity := make([]int, m) // column which is the unit vector of the row initially
+	for i, coef := range lp.rows {
+		r := make([]float64, numCols + 1)
+		sign := 1.0
+		sense := lp.senses[i]
+		if lp.b[i] < 0 {
+			sign = -1
+			flipped[i] = true
+			if sense == LessEqual {
+				sense = GreaterEqual
+			} else if sense == GreaterEqual {
+				sense = LessEqual
+			}
+		}
+		for j, v := range coef {
+			r[j] = sign * v
+		}
+		r[rhs] = sign * lp.b[i]
+		switch sense {
+		case LessEqual:
+			r[n + i] = 1
+			identity[i] = n + i
+		case GreaterEqual:
+			r[n + i] = -1
+			r[n + m + i] = 1
+			identity[i] = n + m + i
+		case Equal:
+			r[n + m + i] = 1
+			identity[i] = n + m + i
+		}
+		tb.t[i] = r
+		tb.basis[i] = identity[i]
+	}
+	isArtificial := func(j int) bool { return j >= n + m }
+
+	// phase 1 minimizes the sum of artificial variables
+	cost := make([]float64, numCols)
+	for i := range identity {
+		if isArtificial(identity[i]) {
+			cost[identity[i]] = -1
+		}
+	}
+	if err := tb.optimize(cost, func(j int) bool { return true }); err != nil {
+		return nil, nil, err
+	}
+	for i, r := range tb.t {
+		if isArtificial(tb.basis[i]) && r[rhs] > simplexEps * float64(m + 1) {
+			return nil, nil, ErrInfeasible
+		}
+	}
+	// drive artificial variables at 0 out of the basis, leaving redundant rows as they are
+	for i, r := range tb.t {
+		if !isArtificial(tb.basis[i]) { continue }
+		for j := 0; j < n + m; j++ {
+			if math.Abs(r[j]) > simplexEps {
+				tb.pivot(i, j)
+				break
+			}
+		}
+	}
+
+	// phase 2
+	cost = make([]float64, numCols)
+	copy(cost, lp.c)
+	tb.bland = false
+	if err := tb.optimize(cost, func(j int) bool { return !isArtificial(j) }); err != nil {
+		return nil, nil, err
+	}
+	x = make([]float64, n)
+	for i, r := range tb.t {
+		if tb.basis[i] < n {
+			x[tb.basis[i]] = r[rhs]
+		}
+	}
+	// y = c_B B^-1, where the columns of B^-1 are the initial unit columns
+	dual = make([]float64, m)
+	for i := range dual {
+		y := 0.0
+		for k, r := range tb.t {
+			y += cost[tb.basis[k]] * r[identity[i]]
+		}
+		if flipped[i] {
+			y = -y
+		}
+		dual[i] = y
+	}
+	return x, dual, nil
+}
@@ -0,0 +1,130 @@
+package mdp
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+)
+
+const (
+	solutionMagic = "GRLV"
+	solutionVersion = 1
+)
+
+// FingerprintMismatchError reports a solution saved for a different Model.
+type FingerprintMismatchError struct {
+	Got, Want uint64
+}
+
+func (e *FingerprintMismatchError) Error() string {
+	return fmt.Sprintf("mdp: model fingerprint mismatch: got %016x, want %016x", e.Got, e.Want)
+}
+
+// Fingerprint returns a hash of the structure of the Model,
+// i.e. states, actions, outcomes and their probabilities regardless of rewards,
+// so that a solution can be reused after rewards are updated.
+func (m *Model) Fingerprint() uint64 {
+	h := fnv.New64a()
+	bw := &binaryWriter{w: bufio.NewWriter(h)}
+	bw.writeUvarint(uint64(len(m.states)))
+	for i := range m.states {
+		bw.writeVarint(int64(m.states[i].id))
+	}
+	bw.writeUvarint(uint64(len(m.actions)))
+	for i := range m.actions {
+		a := &m.actions[i]
+		if a.state == nil {
+			bw.writeVarint(-1)
+			continue
+		}
+		bw.writeVarint(int64(a.state.index))
+		bw.writeUvarint(uint64(len(a.transitions)))
+		for _, tr := range a.transitions {
+			bw.writeVarint(int64(tr.state.index))
+			bw.writeFloat(tr.p)
+		}
+	}
+	bw.w.Flush()
+	return h.Sum64()
+}
+
+// Save writes the solution of the ValueIterator, i.e. V, Q, Policy,
+// absorbing states and parameters, with the fingerprint of its Model.
+func (vi *ValueIterator) Save(w io.Writer) error {
+	bw := &binaryWriter{w: bufio.NewWriter(w)}
+	bw.write([]byte(solutionMagic))
+	bw.writeUvarint(solutionVersion)
+	bw.writeUvarint(vi.model.Fingerprint())
+	bw.writeFloat(vi.alpha)
+	bw.writeFloat(vi.gamma)
+	for _, absorbing := range vi.isAbsorbing {
+		if absorbing {
+			bw.writeUvarint(1)
+		} else {
+			bw.writeUvarint(0)
+		}
+	}
+	for _, values := range [][]float64{vi.V, vi.Q, vi.Policy} {
+		for _, v := range values {
+			bw.writeFloat(v)
+		}
+	}
+	if bw.err == nil {
+		bw.err = bw.w.Flush()
+	}
+	return bw.err
+}
+
+// Load reads a solution written by Save into the ValueIterator.
+// It returns *FingerprintMismatchError if the solution was saved for a different Model.
+// A loaded solution warm-starts RunValueIteration.
+func (vi *ValueIterator) Load(r io.Reader) error {
+	br := &binaryReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(solutionMagic))
+	br.read(magic)
+	if br.err == nil && (string(magic) != solutionMagic || br.readUvarint() != solutionVersion) {
+		return ErrInvalidFormat
+	}
+	fingerprint := br.readUvarint()
+	if want := vi.model.Fingerprint(); br.err == nil && fingerprint != want {
+		return &FingerprintMismatchError{Got: fingerprint, Want: want}
+	}
+	alpha := br.readFloat()
+	gamma := br.readFloat()
+	isAbsorbing := make([]bool, len(vi.isAbsorbing))
+	for i := range isAbsorbing {
+		isAbsorbing[i] = br.readUvarint() == 1
+	}
+	V := make([]float64, len(vi.V))
+	Q := make([]float64, len(vi.Q))
+	Policy := make([]float64, len(vi.Policy))
+	for _, values := range [][]float64{V, Q, Policy} {
+		for i := range values {
+			values[i] = br.readFloat()
+		}
+	}
+	if br.err != nil {
+		if br.err == io.EOF || br.err == io.ErrUnexpectedEOF {
+			return ErrInvalidFormat
+		}
+		return br.err
+	}
+	vi.alpha = alpha
+	vi.gamma = gamma
+	copy(vi.isAbsorbing, isAbsorbing)
+	copy(vi.V, V)
+	copy(vi.Q, Q)
+	copy(vi.Policy, Policy)
+	return nil
+}
+
+// WarmStart sets V to given state values, e.g. a solution of another ValueIterator,
+// so that RunValueIteration starts from them.
+func (vi *ValueIterator) WarmStart(V []float64) error {
+	if len(V) != len(vi.V) {
+		return &DimensionMismatchError{Got: len(V), Want: len(vi.V)}
+	}
+	copy(vi.V, V)
+	return nil
+}
@@ -0,0 +1,55 @@
+package mdp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSolutionSaveLoad(t *testing.T) {
+	sm, _ := NewModel(
+		[]int{0, 1, 2, 3},
+		[]StateTransition{{0, 1, -1}, {1, 2, -1}, {1, 3, 0}, {2, 3, -1}, {3, 0, -1}},
+	)
+	svi := NewValueIterator(sm)
+	run(svi, 2)
+	var buf bytes.Buffer
+	if err := svi.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data := buf.Bytes()
+
+	lvi := NewValueIterator(sm)
+	if err := lvi.Load(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for i := range svi.V {
+		if lvi.V[i] != svi.V[i] {
+			t.Errorf("V@%d: got %.3f, want %.3f", i, lvi.V[i], svi.V[i])
+		}
+	}
+	for i := range svi.Policy {
+		if lvi.Policy[i] != svi.Policy[i] || lvi.Q[i] != svi.Q[i] {
+			t.Errorf("@%d: got %.3f/%.3f, want %.3f/%.3f", i, lvi.Q[i], lvi.Policy[i], svi.Q[i], svi.Policy[i])
+		}
+	}
+
+	// a reward change keeps the fingerprint and the loaded values warm-start Value Iteration
+	sm.UpdateReward([]float64{-1, -3, 0, -1, -1})
+	lvi = NewValueIterator(sm)
+	if err := lvi.Load(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Load after reward update: %v", err)
+	}
+	lvi.RunValueIteration()
+	wantV := []float64{-4, -3, 0, -5}
+	for i, got := range lvi.V {
+		if got != wantV[i] {
+			t.Errorf("warm V@%d: got %.3f, want %.3f", i, got, wantV[i])
+		}
+	}
+
+	other, _ := NewModel([]int{0, 1, 2, 3}, []StateTransition{{0, 1, -1}})
+	err := NewValueIterator(other).Load(bytes.NewReader(data))
+	if _, ok := err.(*FingerprintMismatchError); !ok {
+		t.Errorf("got %v, want *FingerprintMismatchError", err)
+	}
+}
@@ -0,0 +1,184 @@
+package mdp
+
+import (
+	"context"
+)
+
+// Solver is a planner of a Model toward absorbing states.
+// ValueIterator, CSRValueIterator, PolicyIterator and FiniteHorizonSolver implement it,
+// so that a caller such as maxent does not depend on a particular algorithm.
+type Solver interface {
+	// InitAbsorbingState clears absorbing states.
+	InitAbsorbingState()
+	// SetAbsorbingState adds an absorbing state, e.g. a goal.
+	SetAbsorbingState(stateID int) error
+	// Solve computes state values until convergence or cancellation of ctx.
+	Solve(ctx context.Context) error
+	// UpdatePolicy updates the policy from the computed values.
+	UpdatePolicy()
+	// StateActionVisitationContext computes state-action visitation frequency distribution
+	// based on the current policy.
+	StateActionVisitationContext(ctx context.Context, initialStateDist []float64) ([]float64, []float64, error)
+}
+
+// SolverFactory constructs a Solver of a given Model.
+type SolverFactory func(m *Model) Solver
+
+var (
+	_ Solver = (*ValueIterator)(nil)
+	_ Solver = (*CSRValueIterator)(nil)
+	_ Solver = (*PolicyIterator)(nil)
+	_ Solver = (*FiniteHorizonSolver)(nil)
+)
+
+// NewValueIteratorSolver is a SolverFactory which constructs a ValueIterator.
+func NewValueIteratorSolver(m *Model) Solver {
+	return NewValueIterator(m)
+}
+
+// NewPolicyIteratorSolver is a SolverFactory which constructs a PolicyIterator.
+func NewPolicyIteratorSolver(m *Model) Solver {
+	return NewPolicyIterator(m)
+}
+
+// Solve runs Value Iteration until convergence or cancellation of ctx.
+func (vi *ValueIterator) Solve(ctx context.Context) error {
+	_, err := vi.RunValueIterationContext(ctx)
+	return err
+}
+
+// Solve runs Value Iteration until convergence or cancellation of ctx.
+func (vi *CSRValueIterator) Solve(ctx context.Context) error {
+	_, err := vi.RunValueIterationContext(ctx)
+	return err
+}
+
+// Solve runs Policy Iteration until convergence or cancellation of ctx.
+func (pi *PolicyIterator) Solve(ctx context.Context) error {
+	_, err := pi.RunPolicyIterationContext(ctx)
+	return err
+}
+
+// StateActionVisitation computes state-action visitation frequency distribution based on the current policy.
+func (pi *PolicyIterator) StateActionVisitation(initialStateDist []float64) ([]float64, []float64) {
+	stateDist, actionDist, _ := pi.StateActionVisitationContext(context.Background(), initialStateDist)
+	return stateDist, actionDist
+}
+
+// StateActionVisitationContext computes state-action visitation frequency distribution
+// based on the current policy until convergence or cancellation of ctx.
+func (pi *PolicyIterator) StateActionVisitationContext(ctx context.Context, initialStateDist []float64) ([]float64, []float64, error) {
+	return newPolicyVisitor(pi.model, pi.Policy, pi.isAbsorbing, pi.gamma).StateActionVisitationContext(ctx, initialStateDist)
+}
+
+// newPolicyVisitor returns a ValueIterator sharing a given policy and absorbing states
+// to compute their state-action visitation.
+func newPolicyVisitor(model *Model, policy []float64, isAbsorbing []bool, gamma float64) *ValueIterator {
+	return &ValueIterator{
+		model: model,
+		Policy: policy,
+		actionSpace: actionSpace{absorbing{states: model, isAbsorbing: isAbsorbing}},
+		gamma: gamma,
+		convergence: convergence{DefaultOptions()},
+	}
+}
+
+// stateIndexer maps a state ID to its index. Model and CSRModel implement it.
+type stateIndexer interface {
+	stateIndex(stateID int) (int, bool)
+}
+
+func (m *Model) stateIndex(stateID int) (int, bool) {
+	s, ok := m.StateOf[stateID]
+	if !ok {
+		return 0, false
+	}
+	return s.index, true
+}
+
+func (m *CSRModel) stateIndex(stateID int) (int, bool) {
+	s, ok := m.StateOf[stateID]
+	return int(s), ok
+}
+
+// absorbing holds absorbing states, which solvers and environments embed.
+// values are zeroed when their states become absorbing, unless nil.
+type absorbing struct {
+	states stateIndexer
+	isAbsorbing []bool
+	values []float64
+}
+
+func newAbsorbing(states stateIndexer, numStates int, values []float64) absorbing {
+	return absorbing{states: states, isAbsorbing: make([]bool, numStates), values: values}
+}
+
+// SetAbsorbingState sets absorbing states in the Model.
+// An absorbing state represents the state which terminates an episode,
+// and its value is fixed to 0.
+func (ab *absorbing) SetAbsorbingState(stateID int) error {
+	i, ok := ab.states.stateIndex(stateID)
+	if !ok { return &UnknownStateError{stateID} }
+	ab.isAbsorbing[i] = true
+	if ab.values != nil {
+		ab.values[i] = 0
+	}
+	return nil
+}
+
+// InitAbsorbingState initializes absorbing states.
+func (ab *absorbing) InitAbsorbingState() {
+	for i := range ab.isAbsorbing {
+		ab.isAbsorbing[i] = false
+	}
+}
+
+// actionSpace is absorbing for solvers of a Model, which have no actions at absorbing states.
+type actionSpace struct {
+	absorbing
+}
+
+func newActionSpace(model *Model, values []float64) actionSpace {
+	return actionSpace{newAbsorbing(model, len(model.states), values)}
+}
+
+// ToActions returns an action space of a given state as []*Action.
+func (as *actionSpace) ToActions(s *State) []*Action {
+	if as.isAbsorbing[s.index] {
+		return s.actions[:0]
+	}
+	return s.actions
+}
+
+// greedyPolicy is actionSpace for solvers whose policy is deterministic and greedy in q.
+type greedyPolicy struct {
+	actionSpace
+	model *Model
+	q, policy []float64
+}
+
+func newGreedyPolicy(model *Model, V, Q, policy []float64) greedyPolicy {
+	return greedyPolicy{actionSpace: newActionSpace(model, V), model: model, q: Q, policy: policy}
+}
+
+// UpdatePolicy updates the deterministic policy which is greedy in Q.
+func (gp *greedyPolicy) UpdatePolicy() {
+	updateGreedyPolicy(gp.model, gp.q, gp.policy, gp.ToActions)
+}
+
+// updateGreedyPolicy sets policy to the action of each state with the highest q
+// among those given by toActions.
+func updateGreedyPolicy(model *Model, q, policy []float64, toActions func(s *State) []*Action) {
+	for stateIdx := range model.states {
+		actions := toActions(&model.states[stateIdx])
+		if len(actions) == 0 { continue }
+		best := actions[0]
+		for _, a := range actions {
+			policy[a.index] = 0
+			if q[best.index] < q[a.index] {
+				best = a
+			}
+		}
+		policy[best.index] = 1
+	}
+}
@@ -0,0 +1,53 @@
+package mdp
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSolver(t *testing.T) {
+	gm := newGridModel(6, 5)
+	factories := map[string]SolverFactory{
+		"ValueIterator": NewValueIteratorSolver,
+		"PolicyIterator": NewPolicyIteratorSolver,
+		"CSRValueIterator": func(m *Model) Solver { return NewCSRValueIterator(m.CSR()) },
+	}
+	start := gm.StateOf[5 * 5 + 4].index
+	initialStateDist := make([]float64, gm.NumStates())
+	initialStateDist[start] = 1
+	for name, newSolver := range factories {
+		solver := newSolver(gm)
+		solver.InitAbsorbingState()
+		if err := solver.SetAbsorbingState(0); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := solver.Solve(context.Background()); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		solver.UpdatePolicy()
+		stateDist, actionDist, err := solver.StateActionVisitationContext(context.Background(), initialStateDist)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// a shortest path from (5, 4) to (0, 0) takes 9 moves and reaches the goal once.
+		steps := 0.0
+		for _, d := range actionDist {
+			steps += d
+		}
+		if math.Abs(steps - 9) > 1e-3 {
+			t.Errorf("%s steps: got %.4f, want 9", name, steps)
+		}
+		if math.Abs(stateDist[gm.StateOf[0].index] - 1) > 1e-3 {
+			t.Errorf("%s goal visitation: got %.4f, want 1", name, stateDist[gm.StateOf[0].index])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pi := NewPolicyIterator(gm)
+	pi.SetAbsorbingState(0)
+	if err := pi.Solve(ctx); err != context.Canceled {
+		t.Errorf("canceled Solve: got %v, want %v", err, context.Canceled)
+	}
+}
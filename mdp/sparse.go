@@ -0,0 +1,130 @@
+package mdp
+
+import (
+	"math"
+	"github.com/misteroda/go-rl/base"
+)
+
+const (
+	breakdownEps = 1e-8
+)
+
+// sparseMatrix is a square matrix in compressed sparse row format.
+type sparseMatrix struct {
+	n int
+	rowPtr []int
+	cols []int
+	values []float64
+}
+
+func newSparseMatrix(n int) *sparseMatrix {
+	return &sparseMatrix{
+		n: n,
+		rowPtr: make([]int, 1, n + 1),
+	}
+}
+
+// appendElement appends an element to the last row.
+// Duplicate elements in a row are summed up.
+func (m *sparseMatrix) appendElement(col int, v float64) {
+	m.cols = append(m.cols, col)
+	m.values = append(m.values, v)
+}
+
+// closeRow finishes the current row and starts a new one.
+func (m *sparseMatrix) closeRow() {
+	m.rowPtr = append(m.rowPtr, len(m.cols))
+}
+
+// mulVec computes y = Ax.
+func (m *sparseMatrix) mulVec(x, y []float64) {
+	for i := 0; i < m.n; i++ {
+		v := 0.0
+		for k := m.rowPtr[i]; k < m.rowPtr[i+1]; k++ {
+			v += m.values[k] * x[m.cols[k]]
+		}
+		y[i] = v
+	}
+}
+
+// residual computes r = b - Ax and returns its norm.
+func (m *sparseMatrix) residual(b, x, r []float64) float64 {
+	m.mulVec(x, r)
+	for i := range r {
+		r[i] = b[i] - r[i]
+	}
+	return base.Vector(r).Norm()
+}
+
+// solveBiCGSTAB solves Ax = b by the stabilized bi-conjugate gradient method.
+// x is used as an initial guess and is overwritten by the solution.
+// The method is restarted from the true residual on (near) breakdown.
+// It returns false if it does not converge in maxIter iterations.
+func (m *sparseMatrix) solveBiCGSTAB(b, x []float64, tol float64, maxIter int) bool {
+	n := m.n
+	r := make([]float64, n)
+	rHat := make([]float64, n)
+	p := make([]float64, n)
+	v := make([]float64, n)
+	s := make([]float64, n)
+	t := make([]float64, n)
+
+	tol *= math.Max(1.0, base.Vector(b).Norm())
+	restart := true
+	var rho, alpha, omega float64
+	for iter := 0; iter < maxIter; iter++ {
+		if restart {
+			if m.residual(b, x, r) < tol {
+				return true
+			}
+			copy(rHat, r)
+			base.Vector(p).Fill(0.0)
+			base.Vector(v).Fill(0.0)
+			rho, alpha, omega = 1.0, 1.0, 1.0
+			restart = false
+		}
+		rhoNext := base.Vector(rHat).Dot(r)
+		if math.Abs(rhoNext) < breakdownEps * base.Vector(rHat).Norm() * base.Vector(r).Norm() {
+			restart = true
+			continue
+		}
+		beta := (rhoNext / rho) * (alpha / omega)
+		for i := range p {
+			p[i] = r[i] + beta * (p[i] - omega * v[i])
+		}
+		m.mulVec(p, v)
+		den := base.Vector(rHat).Dot(v)
+		if math.Abs(den) < breakdownEps * base.Vector(rHat).Norm() * base.Vector(v).Norm() {
+			restart = true
+			continue
+		}
+		alpha = rhoNext / den
+		for i := range s {
+			s[i] = r[i] - alpha * v[i]
+		}
+		if base.Vector(s).Norm() < tol {
+			for i := range x {
+				x[i] += alpha * p[i]
+			}
+			restart = true
+			continue
+		}
+		m.mulVec(s, t)
+		tt := base.Vector(t).Dot(t)
+		if tt == 0 {
+			restart = true
+			continue
+		}
+		omega = base.Vector(t).Dot(s) / tt
+		for i := range x {
+			x[i] += alpha * p[i] + omega * s[i]
+			r[i] = s[i] - omega * t[i]
+		}
+		if base.Vector(r).Norm() < tol || omega == 0 {
+			restart = true
+			continue
+		}
+		rho = rhoNext
+	}
+	return m.residual(b, x, r) < tol
+}
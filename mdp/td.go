@@ -0,0 +1,264 @@
+package mdp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// TDMethod is a temporal-difference control method.
+type TDMethod int
+
+const (
+	// QLearning bootstraps from the best action at the next state.
+	QLearning TDMethod = iota
+	// SARSA bootstraps from the action taken at the next state.
+	SARSA
+	// ExpectedSARSA bootstraps from the expected value under the exploration policy.
+	ExpectedSARSA
+)
+
+func (m TDMethod) String() string {
+	switch m {
+	case QLearning:
+		return "Q-learning"
+	case SARSA:
+		return "SARSA"
+	case ExpectedSARSA:
+		return "Expected SARSA"
+	}
+	return fmt.Sprintf("TDMethod(%d)", int(m))
+}
+
+// Exploration is a rule to choose actions from Q values.
+type Exploration int
+
+const (
+	// EpsilonGreedy takes a uniformly random action with probability epsilon
+	// and a greedy action otherwise.
+	EpsilonGreedy Exploration = iota
+	// Softmax takes an action with probability proportional to exp(Q / temperature).
+	Softmax
+)
+
+// TDAgent represents a tabular temporal-difference control agent.
+// Q is indexed by action index, as ValueIterator.Q is for a Model,
+// so learned and planned values can be compared on the same Model.
+type TDAgent struct {
+	env Env
+	Q []float64 // state-action values
+	method TDMethod
+	exploration Exploration
+	epsilon float64
+	temperature float64
+	learningRate float64
+	gamma float64 // discount factor
+	maxSteps int
+	rand *rand.Rand
+}
+
+// NewTDAgent constructs a TDAgent instance from a given Env, a number of actions and a method.
+// It explores epsilon-greedily with epsilon 0.1 and learns with rate 0.1 by default.
+func NewTDAgent(env Env, numActions int, method TDMethod) *TDAgent {
+	return &TDAgent{
+		env: env,
+		Q: make([]float64, numActions),
+		method: method,
+		exploration: EpsilonGreedy,
+		epsilon: 0.1,
+		temperature: 1,
+		learningRate: 0.1,
+		gamma: 1,
+		maxSteps: 10000,
+		rand: rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+// SetRand sets the source of randomness of exploration, e.g. seeded for reproducibility.
+func (ag *TDAgent) SetRand(r *rand.Rand) {
+	ag.rand = r
+}
+
+// SetEpsilonGreedy sets epsilon-greedy exploration.
+func (ag *TDAgent) SetEpsilonGreedy(epsilon float64) {
+	ag.exploration = EpsilonGreedy
+	ag.epsilon = epsilon
+}
+
+// SetSoftmax sets softmax exploration.
+func (ag *TDAgent) SetSoftmax(temperature float64) {
+	ag.exploration = Softmax
+	ag.temperature = temperature
+}
+
+// SetLearningRate sets a learning rate.
+func (ag *TDAgent) SetLearningRate(learningRate float64) {
+	ag.learningRate = learningRate
+}
+
+// SetGamma sets a discount factor.
+func (ag *TDAgent) SetGamma(gamma float64) {
+	ag.gamma = gamma
+}
+
+// SetMaxSteps sets the maximum number of steps of an episode.
+func (ag *TDAgent) SetMaxSteps(maxSteps int) {
+	ag.maxSteps = maxSteps
+}
+
+// Init initializes Q.
+func (ag *TDAgent) Init() {
+	for i := range ag.Q {
+		ag.Q[i] = 0
+	}
+}
+
+// actionDist returns the exploration policy over actions.
+func (ag *TDAgent) actionDist(actions []int) []float64 {
+	p := make([]float64, len(actions))
+	switch ag.exploration {
+	case Softmax:
+		maxQ := math.Inf(-1)
+		for _, a := range actions {
+			maxQ = math.Max(maxQ, ag.Q[a])
+		}
+		z := 0.0
+		for i, a := range actions {
+			p[i] = math.Exp((ag.Q[a] - maxQ) / ag.temperature)
+			z += p[i]
+		}
+		for i := range p {
+			p[i] /= z
+		}
+	default:
+		best := ag.greedy(actions)
+		for i := range p {
+			p[i] = ag.epsilon / float64(len(actions))
+		}
+		p[best] += 1 - ag.epsilon
+	}
+	return p
+}
+
+// greedy returns the position of the best action in actions.
+func (ag *TDAgent) greedy(actions []int) int {
+	best := 0
+	for i, a := range actions {
+		if ag.Q[a] > ag.Q[actions[best]] {
+			best = i
+		}
+	}
+	return best
+}
+
+func (ag *TDAgent) chooseAction(actions []int) int {
+	p := ag.actionDist(actions)
+	r := ag.rand.Float64()
+	cumP := 0.0
+	for i, x := range p {
+		cumP += x
+		if r < cumP {
+			return actions[i]
+		}
+	}
+	return actions[len(actions)-1]
+}
+
+// target returns the value bootstrapped from the next state,
+// given the action to be taken there for SARSA.
+func (ag *TDAgent) target(actions []int, next int) float64 {
+	switch ag.method {
+	case SARSA:
+		return ag.Q[next]
+	case ExpectedSARSA:
+		v := 0.0
+		for i, p := range ag.actionDist(actions) {
+			v += p * ag.Q[actions[i]]
+		}
+		return v
+	}
+	return ag.Q[actions[ag.greedy(actions)]]
+}
+
+// RunEpisode runs an episode while updating Q and returns the discounted return.
+// Episodes end when the Env is done, at a state without actions, or after the maximum steps.
+// Only states without actions are terminal, so the last update of an episode
+// truncated by the Env or the maximum steps bootstraps from Q of the next state.
+func (ag *TDAgent) RunEpisode() (float64, error) {
+	stateID := ag.env.Reset()
+	actions := ag.env.Actions(stateID)
+	if len(actions) == 0 {
+		return 0, nil
+	}
+	a := ag.chooseAction(actions)
+	ret, discount := 0.0, 1.0
+	for k := 0; k < ag.maxSteps; k++ {
+		nextID, r, done, err := ag.env.Step(a)
+		if err != nil {
+			return ret, err
+		}
+		ret += discount * r
+		discount *= ag.gamma
+		target := r
+		nextActions := ag.env.Actions(nextID)
+		next := -1
+		if len(nextActions) > 0 {
+			next = ag.chooseAction(nextActions)
+			target += ag.gamma * ag.target(nextActions, next)
+		}
+		ag.Q[a] += ag.learningRate * (target - ag.Q[a])
+		if done || next < 0 {
+			break
+		}
+		a = next
+	}
+	return ret, nil
+}
+
+// Train runs numEpisodes episodes and returns their returns.
+func (ag *TDAgent) Train(numEpisodes int) ([]float64, error) {
+	return ag.TrainContext(context.Background(), numEpisodes)
+}
+
+// TrainContext runs Train until completion or cancellation of ctx.
+// ctx is checked at each episode.
+func (ag *TDAgent) TrainContext(ctx context.Context, numEpisodes int) ([]float64, error) {
+	returns := make([]float64, 0, numEpisodes)
+	for i := 0; i < numEpisodes; i++ {
+		if ctx.Err() != nil {
+			return returns, ctx.Err()
+		}
+		ret, err := ag.RunEpisode()
+		if err != nil {
+			return returns, err
+		}
+		returns = append(returns, ret)
+	}
+	return returns, nil
+}
+
+// Greedy returns the index of the best action at a state, or -1 if it has no actions.
+func (ag *TDAgent) Greedy(stateID int) int {
+	actions := ag.env.Actions(stateID)
+	if len(actions) == 0 {
+		return -1
+	}
+	return actions[ag.greedy(actions)]
+}
+
+// Policy returns the greedy policy over given states indexed by action index,
+// comparable with ValueIterator.Policy.
+func (ag *TDAgent) Policy(stateIDs []int) []float64 {
+	policy := make([]float64, len(ag.Q))
+	for _, id := range stateIDs {
+		if a := ag.Greedy(id); a >= 0 {
+			policy[a] = 1
+		}
+	}
+	return policy
+}
+
+func (ag *TDAgent) String() string {
+	return fmt.Sprintf("%s agent: %v", ag.method, ag.Q)
+}
@@ -0,0 +1,99 @@
+package mdp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestTDAgent(t *testing.T) {
+	gm := newGridModel(5, 4)
+	vi := NewValueIterator(gm)
+	vi.SetAbsorbingState(0)
+	vi.RunValueIteration()
+
+	initialStateDist := make([]float64, gm.NumStates())
+	for i := 1; i < len(initialStateDist); i++ {
+		initialStateDist[i] = 1 / float64(len(initialStateDist) - 1)
+	}
+	env, err := NewModelEnv(gm, initialStateDist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.SetAbsorbingState(0)
+	for _, method := range []TDMethod{QLearning, SARSA, ExpectedSARSA} {
+		for _, softmax := range []bool{false, true} {
+			env.SetRand(rand.New(rand.NewSource(1)))
+			ag := NewTDAgent(env, env.NumActions(), method)
+			ag.SetRand(rand.New(rand.NewSource(2)))
+			ag.SetLearningRate(0.5)
+			if softmax {
+				ag.SetSoftmax(0.2)
+			}
+			if _, err := ag.Train(2000); err != nil {
+				t.Fatal(err)
+			}
+			// the greedy policy must follow a shortest path from every state
+			for _, id := range gm.StateIDs() {
+				if id == 0 { continue }
+				a := ag.Greedy(id)
+				if a < 0 || math.Abs(vi.Q[a] - vi.V[gm.StateOf[id].index]) > 1e-9 {
+					t.Errorf("%s (softmax=%v) action at %d: got %d, Q %v", method, softmax, id, a, ag)
+					break
+				}
+			}
+		}
+	}
+
+	if _, _, _, err := env.Step(len(gm.actions)); err == nil {
+		t.Errorf("got nil, want *UnknownActionError")
+	}
+	if _, err := NewModelEnv(gm, nil); err == nil {
+		t.Errorf("got nil, want *DimensionMismatchError")
+	}
+	if _, err := NewModelEnv(gm, make([]float64, gm.NumStates())); err == nil {
+		t.Errorf("got nil, want *InvalidDistributionError for all zero weights")
+	}
+	initialStateDist[1] = -1
+	if _, err := NewModelEnv(gm, initialStateDist); err == nil {
+		t.Errorf("got nil, want *InvalidDistributionError for a negative weight")
+	}
+}
+
+func TestTDAgentStochastic(t *testing.T) {
+	rm := newRiskModel()
+	env, _ := NewModelEnv(rm, []float64{1, 0, 0, 0})
+	env.SetRand(rand.New(rand.NewSource(1)))
+	env.SetAbsorbingState(3)
+	ag := NewTDAgent(env, env.NumActions(), QLearning)
+	ag.SetRand(rand.New(rand.NewSource(2)))
+	ag.SetLearningRate(0.002)
+	ag.SetEpsilonGreedy(0.3)
+	if _, err := ag.Train(50000); err != nil {
+		t.Fatal(err)
+	}
+	// the risky action has expected return -6 against -10
+	if a := ag.Greedy(0); a != 1 {
+		t.Errorf("action at 0: got %d, want 1 with Q %v", a, ag.Q)
+	}
+	if math.Abs(ag.Q[1] + 6) > 2 {
+		t.Errorf("Q[1]: got %.3f, want about -6", ag.Q[1])
+	}
+}
+
+func TestTDAgentTruncation(t *testing.T) {
+	chain, _ := NewModel([]int{0, 1, 2}, []StateTransition{{0, 1, -1}, {1, 2, -1}})
+	env, _ := NewModelEnv(chain, []float64{1, 0, 0})
+	env.SetAbsorbingState(2)
+	env.SetMaxSteps(1)
+	ag := NewTDAgent(env, env.NumActions(), QLearning)
+	ag.SetLearningRate(1)
+	ag.Q[1] = -1
+	if _, err := ag.RunEpisode(); err != nil {
+		t.Fatal(err)
+	}
+	// the episode is truncated at 1, which is not terminal
+	if ag.Q[0] != -2 {
+		t.Errorf("Q[0]: got %.3f, want -2", ag.Q[0])
+	}
+}
@@ -1,6 +1,7 @@
 package mdp
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
@@ -8,11 +9,12 @@ import (
 )
 
 const (
-	pqSize = 10000
 	minTDError = 0.001
 	minSDError = 0.0001
 	maxIterations = 1000000
 	numAnnealing = 7
+	annealingRate = 0.5
+	ctxCheckInterval = 1024
 )
 
 // ValueIterator represents Value Iteration algorithm.
@@ -21,41 +23,48 @@ type ValueIterator struct {
 	V []float64 // state values
 	Q []float64 // state-action values
 	Policy []float64
-	isAbsorbing []bool
+	actionSpace
 	alpha float64 // temperature parameter for softmax operator
+	gamma float64 // discount factor
+	convergence
+	progress ProgressFunc
 }
 
 // NewValueIterator constructs a ValueIterator instance from a given Model.
 func NewValueIterator(model *Model) *ValueIterator {
+	return NewValueIteratorWithOptions(model, DefaultOptions())
+}
+
+// NewValueIteratorWithOptions constructs a ValueIterator instance
+// from a given Model and convergence settings.
+func NewValueIteratorWithOptions(model *Model, opts Options) *ValueIterator {
+	V := make([]float64, len(model.states))
 	vi := ValueIterator{
 		model: model,
-		V: make([]float64, len(model.states)),
+		V: V,
 		Q: make([]float64, len(model.actions)),
 		Policy: make([]float64, len(model.actions)),
-		isAbsorbing: make([]bool, len(model.states)),
+		actionSpace: newActionSpace(model, V),
+		gamma: 1.0,
+		convergence: convergence{opts},
 	}
 	return &vi
 }
 
-// SetAbsorbingState sets absorbing states in the Model.
-// An absorbing state represents the state which terminates an episode.
-func (vi *ValueIterator) SetAbsorbingState(stateID int) bool {
-	state, ok := vi.model.StateOf[stateID]
-	if !ok { return false }
-	vi.isAbsorbing[state.index] = true
-	return true
-}
-
 // SetAlpha sets a value of alpha.
 func (vi *ValueIterator) SetAlpha(alpha float64) {
 	vi.alpha = alpha
 }
 
-// InitAbsorbingState initializes absorbing states.
-func (vi *ValueIterator) InitAbsorbingState() {
-	for i := range vi.isAbsorbing {
-		vi.isAbsorbing[i] = false
-	}
+// SetGamma sets a discount factor.
+// A gamma less than 1 makes Value Iteration converge without absorbing states.
+func (vi *ValueIterator) SetGamma(gamma float64) {
+	vi.gamma = gamma
+}
+
+// SetProgressFunc sets a hook which is called at the end of each annealing stage.
+func (vi *ValueIterator) SetProgressFunc(progress ProgressFunc) {
+	vi.progress = progress
 }
 
 // Init initializes ValueIterator instance.
@@ -65,44 +74,77 @@ func (vi *ValueIterator) Init() {
 	base.Vector(vi.Policy).Fill(0.0)
 	vi.InitAbsorbingState()
 	vi.alpha = 0.0
+	vi.gamma = 1.0
 }
 
-// ToActions returns an action space of a given state as []*Action.
-func (vi *ValueIterator) ToActions(s *State) []*Action {
-	if vi.isAbsorbing[s.index] {
-		return s.actions[:0]
-	}
-	return s.actions
+// RunValueIteration runs Value Iteration algorithm and updates V and Q.
+// It starts from the current V, so a previous solution warm-starts it.
+func (vi *ValueIterator) RunValueIteration() ConvergenceReport {
+	report, _ := vi.RunValueIterationContext(context.Background())
+	return report
 }
 
-// RunValueIteration runs Value Iteration algorithm and updates V and Q.
-func (vi *ValueIterator) RunValueIteration() {
+// RunValueIterationContext runs Value Iteration algorithm and updates V and Q
+// until convergence or cancellation of ctx.
+// If ctx is done, it returns ctx.Err() leaving V and Q partially updated.
+// It returns *InvalidOptionError if the convergence settings are invalid.
+func (vi *ValueIterator) RunValueIterationContext(ctx context.Context) (ConvergenceReport, error) {
 	m := vi.model
+	opts := &vi.opts
+	if err := opts.Validate(); err != nil {
+		return ConvergenceReport{}, err
+	}
 	pq := NewPriorityQueue(len(m.states))
-	var td float64
-	var j int
-	tdThreshold := minTDError * math.Pow(2, float64(numAnnealing - 1))
-	for i := 0; i < numAnnealing; i++ {
+	report := ConvergenceReport{Iterations: make([]int, opts.NumAnnealing)}
+	tdThreshold := opts.initialThreshold(opts.MinTDError)
+	for i := 0; i < opts.NumAnnealing; i++ {
 		for stateIdx := range m.states {
 			s := &m.states[stateIdx]
-			td = vi.bellmanBackup(s)
-			if td > tdThreshold && pq.Size() < pqSize {
+			td := vi.bellmanBackup(s)
+			if td > tdThreshold && opts.hasRoom(pq) {
 				pq.Push(stateIdx, td)
 			}
 		}
-		for j = 0; j < maxIterations && pq.Size() > 0; j++ {
-			idx, _ := pq.Pop()
-			for _, tr := range m.states[idx].transitions {
-				s := tr.action.state
-				td = vi.bellmanBackup(s)
-				if td > tdThreshold && pq.Size() < pqSize {
-					pq.Push(s.index, td)
-				}
+		j, td, err := vi.sweep(ctx, pq, tdThreshold)
+		if err != nil {
+			report.Iterations[i] = j
+			return report, err
+		}
+		report.Iterations[i] = j
+		if pq.Size() > 0 {
+			report.HitMaxIterations = true
+		}
+		if vi.progress != nil {
+			vi.progress(Progress{Stage: i, Iterations: j, Threshold: tdThreshold, Residual: td, Remaining: pq.Size()})
+		}
+		tdThreshold *= opts.AnnealingRate
+	}
+	for stateIdx := range m.states {
+		report.Residual = math.Max(report.Residual, vi.bellmanBackup(&m.states[stateIdx]))
+	}
+	return report, nil
+}
+
+// sweep pops states from the priority queue and backs up their predecessors
+// until no TD error exceeds the threshold.
+// It returns the number of iterations and the last TD error.
+func (vi *ValueIterator) sweep(ctx context.Context, pq *PriorityQueue, tdThreshold float64) (j int, td float64, err error) {
+	m := vi.model
+	opts := &vi.opts
+	for j = 0; j < opts.MaxIterations && pq.Size() > 0; j++ {
+		if j % ctxCheckInterval == 0 && ctx.Err() != nil {
+			return j, td, ctx.Err()
+		}
+		idx, _ := pq.Pop()
+		for _, tr := range m.states[idx].transitions {
+			s := tr.action.state
+			td = vi.bellmanBackup(s)
+			if td > tdThreshold && opts.hasRoom(pq) {
+				pq.Push(s.index, td)
 			}
 		}
-		// fmt.Printf("Annealing #%d { iters: %d, theta: %.3f, td: %.3f }\n", i, j, tdThreshold, td)
-		tdThreshold *= 0.5
 	}
+	return j, td, nil
 }
 
 // UpdatePolicy updates policy based on current state-action values.
@@ -111,8 +153,8 @@ func (vi *ValueIterator) UpdatePolicy() {
 	for stateIdx := range m.states {
 		s := &m.states[stateIdx]
 		actions := vi.ToActions(s)
-		if len(actions) == 0 { continue }
-		bestAction := vi.bestAction(actions)
+		bestAction, err := vi.bestAction(actions)
+		if err != nil { continue }
 
 		if vi.alpha == 0 {
 			for _, a := range actions {
@@ -136,19 +178,23 @@ func (vi *ValueIterator) UpdatePolicy() {
 
 func (vi *ValueIterator) bellmanBackup(s *State) (tdError float64) {
 	actions := vi.ToActions(s)
-	if len(actions) == 0 { return }
 	for _, a := range actions {
-		vi.Q[a.index] = a.transition.r + vi.V[a.transition.state.index]
+		q := 0.0
+		for _, tr := range a.transitions {
+			q += tr.p * (tr.r + vi.gamma * vi.V[tr.state.index])
+		}
+		vi.Q[a.index] = q
 	}
-	v := vi.softMax(s.actions)
+	v, err := vi.softMax(actions)
+	if err != nil { return }
 	tdError = math.Abs(v - vi.V[s.index])	
 	vi.V[s.index] = v
 	return
 }
 
-func (vi *ValueIterator) softMax(actions []*Action) float64 {
+func (vi *ValueIterator) softMax(actions []*Action) (float64, error) {
 	if len(actions) == 0 {
-		panic("model: zero slice length")
+		return 0, ErrNoAction
 	}
 	maxQ := vi.Q[actions[0].index]
 	for _, a := range actions[1:] {
@@ -157,18 +203,18 @@ func (vi *ValueIterator) softMax(actions []*Action) float64 {
 		}
 	}
 	if vi.alpha == 0 {
-		return maxQ
+		return maxQ, nil
 	}	
 	var lse float64
 	for _, a := range actions {
 		lse += math.Exp((vi.Q[a.index] - maxQ) / vi.alpha)
 	}
-	return vi.alpha * math.Log(lse) + maxQ
+	return vi.alpha * math.Log(lse) + maxQ, nil
 }
 
-func (vi *ValueIterator) bestAction(actions []*Action) *Action {
+func (vi *ValueIterator) bestAction(actions []*Action) (*Action, error) {
 	if len(actions) == 0 {
-		panic("model: zero slice length")
+		return nil, ErrNoAction
 	}
 	maxA := actions[0]
 	maxQ := vi.Q[maxA.index]
@@ -178,48 +224,70 @@ func (vi *ValueIterator) bestAction(actions []*Action) *Action {
 			maxQ = vi.Q[a.index]
 		}
 	}
-	return maxA
+	return maxA, nil
 }
 
-func (vi *ValueIterator) sampleAction(actions []*Action) *Action {
+func (vi *ValueIterator) sampleAction(actions []*Action) (*Action, error) {
+	return vi.actionAt(actions, rand.Float64())
+}
+
+// actionAt returns the action at r in [0, 1) of the cumulative probabilities of the policy.
+func (vi *ValueIterator) actionAt(actions []*Action, r float64) (*Action, error) {
 	if len(actions) == 0 {
-		panic("model: zero slice length")
+		return nil, ErrNoAction
 	}
 	cumP := 0.0
-	r := rand.Float64()
 	for _, a := range actions[:len(actions)-1] {
 		cumP += vi.Policy[a.index]
 		if r < cumP {
-			return a
+			return a, nil
 		}
 	}
-	return actions[len(actions) - 1]
+	return actions[len(actions) - 1], nil
+}
+
+func sampleTransition(a *Action) *Transition {
+	return transitionAt(a, rand.Float64())
+}
+
+// transitionAt returns the transition of an action at r in [0, 1) of its cumulative probabilities.
+func transitionAt(a *Action, r float64) *Transition {
+	cumP := 0.0
+	for _, tr := range a.transitions[:len(a.transitions)-1] {
+		cumP += tr.p
+		if r < cumP {
+			return tr
+		}
+	}
+	return a.transitions[len(a.transitions) - 1]
 }
 
 // GenerateTrajectory generates trajectory of given a start and a goal state based on current policy.
-func (vi *ValueIterator) GenerateTrajectory(startID, goalID, maxSteps int) (tr []int, ok bool) {
+// If the goal is not reached, the trajectory so far is returned with
+// *DeadEndError or *UnreachableGoalError.
+func (vi *ValueIterator) GenerateTrajectory(startID, goalID, maxSteps int) ([]int, error) {
 	m := vi.model
 	startState, ok := m.StateOf[startID]
-	if !ok { return }
+	if !ok { return nil, &UnknownStateError{startID} }
 	goalState, ok := m.StateOf[goalID]
-	if !ok { return }
+	if !ok { return nil, &UnknownStateError{goalID} }
 	s := startState
-	tr = append(tr, s.id)
+	tr := []int{s.id}
+	if s == goalState {
+		return tr, nil
+	}
 	for i := 0; i < maxSteps; i++ {
-		actions := vi.ToActions(s)
-		if len(s.actions) == 0 {
-			ok = false
-			return
+		a, err := vi.sampleAction(vi.ToActions(s))
+		if err != nil {
+			return tr, &DeadEndError{s.id}
 		}
-		s = vi.sampleAction(actions).transition.state
+		s = sampleTransition(a).state
 		tr = append(tr, s.id)
 		if s == goalState {
-			ok = true
-			return
+			return tr, nil
 		}
 	}
-	ok = false
-	return
+	return tr, &UnreachableGoalError{StartID: startID, GoalID: goalID, Steps: maxSteps}
 }
 
 
@@ -227,14 +295,27 @@ func (vi *ValueIterator) computeStateDist(s *State, actionDist []float64) float6
 	d := 0.0
 	for _, tr := range s.transitions {
 		if vi.isAbsorbing[tr.action.state.index] { continue }
-		d += actionDist[tr.action.index]
+		d += actionDist[tr.action.index] * tr.p
 	}
-	return d
+	return vi.gamma * d
 }
 
 // StateActionVisitation computes state-action visitation frequency distribution based on the current policy.
+// If gamma is less than 1, the result is the discounted occupancy.
 func (vi *ValueIterator) StateActionVisitation(initialStateDist []float64) ([]float64, []float64) {
+	stateDist, actionDist, _ := vi.StateActionVisitationContext(context.Background(), initialStateDist)
+	return stateDist, actionDist
+}
+
+// StateActionVisitationContext computes state-action visitation frequency distribution
+// based on the current policy until convergence or cancellation of ctx.
+// If ctx is done, it returns ctx.Err() with the distributions computed so far.
+// It returns *InvalidOptionError if the convergence settings are invalid.
+func (vi *ValueIterator) StateActionVisitationContext(ctx context.Context, initialStateDist []float64) ([]float64, []float64, error) {
 	m := vi.model
+	if err := vi.opts.Validate(); err != nil {
+		return nil, nil, err
+	}
 	stateDist := make([]float64, len(m.states))
 	copy(stateDist, initialStateDist)
 	actionDist := make([]float64, len(m.actions))
@@ -242,8 +323,9 @@ func (vi *ValueIterator) StateActionVisitation(initialStateDist []float64) ([]fl
 	pq := NewPriorityQueue(len(stateDist))
 	var sd float64
 	var j, idx int
-	sdThreshold := minSDError * math.Pow(2, float64(numAnnealing - 1))
-	for i := 0; i < numAnnealing; i++ {
+	opts := &vi.opts
+	sdThreshold := opts.initialThreshold(opts.MinSDError)
+	for i := 0; i < opts.NumAnnealing; i++ {
 		if i == 0 {
 			for idx, sd = range initialStateDist {
 				if sd > 0 {
@@ -257,39 +339,49 @@ func (vi *ValueIterator) StateActionVisitation(initialStateDist []float64) ([]fl
 				if len(actions) == 0 || stateDist[stateIdx] < sdThreshold { continue }
 				for _, a := range actions {
 					actionDist[a.index] = stateDist[stateIdx] * vi.Policy[a.index]
-					nextState := a.transition.state
-					idx := nextState.index
-					sd = stateDist[idx]
-					stateDist[idx] = initialStateDist[idx] + vi.computeStateDist(nextState, actionDist)
-					sd = math.Abs(sd - stateDist[idx])
-					if sd > sdThreshold && pq.Size() < pqSize {
-						pq.Push(stateIdx, sd)
+					for _, tr := range a.transitions {
+						nextState := tr.state
+						idx := nextState.index
+						sd = stateDist[idx]
+						stateDist[idx] = initialStateDist[idx] + vi.computeStateDist(nextState, actionDist)
+						sd = math.Abs(sd - stateDist[idx])
+						if sd > sdThreshold && opts.hasRoom(pq) {
+							pq.Push(stateIdx, sd)
+						}
 					}
 				}
 			}
 		}
 		
-		for j = 0; j < maxIterations && pq.Size() > 0; j++ {
+		for j = 0; j < opts.MaxIterations && pq.Size() > 0; j++ {
+			if j % ctxCheckInterval == 0 && ctx.Err() != nil {
+				return stateDist, actionDist, ctx.Err()
+			}
 			stateIdx, _ := pq.Pop()
 			s := &m.states[stateIdx]
 			actions := vi.ToActions(s)
 			if len(actions) == 0 || stateDist[stateIdx] < sdThreshold { continue }
 			for _, a := range actions {
 				actionDist[a.index] = stateDist[stateIdx] * vi.Policy[a.index]
-				nextState := a.transition.state
-				idx := nextState.index
-				sd = stateDist[idx]
-				stateDist[idx] = initialStateDist[idx] + vi.computeStateDist(nextState, actionDist)
-				sd = math.Abs(sd - stateDist[idx])
-				if sd > sdThreshold && pq.Size() < pqSize {
-					pq.Push(idx, sd)
+				for _, tr := range a.transitions {
+					nextState := tr.state
+					idx := nextState.index
+					sd = stateDist[idx]
+					stateDist[idx] = initialStateDist[idx] + vi.computeStateDist(nextState, actionDist)
+					sd = math.Abs(sd - stateDist[idx])
+					if sd > sdThreshold && opts.hasRoom(pq) {
+						pq.Push(idx, sd)
+					}
 				}
 			}
 		}
-		sdThreshold *= 0.5
+		if vi.progress != nil {
+			vi.progress(Progress{Stage: i, Iterations: j, Threshold: sdThreshold, Residual: sd, Remaining: pq.Size()})
+		}
+		sdThreshold *= opts.AnnealingRate
 	}
 
-	return stateDist, actionDist
+	return stateDist, actionDist, nil
 }
 
 func (vi *ValueIterator) String() string {
@@ -1,11 +1,13 @@
 package mdp
 
 import (
+	"context"
+	"math"
 	"testing"
 )
 
 var (
-	m = NewModel(
+	m, _ = NewModel(
 		[]int{0, 1, 2, 3},
 		[]StateTransition{{0, 1, -1}, {1, 2, -1}, {1, 3, 0}, {2, 3, -1}, {3, 0, -1}},
 	)
@@ -15,9 +17,8 @@ var (
 
 func run(vi *ValueIterator, goal int) {
 	vi.Init()
-	ok := vi.SetAbsorbingState(goal)
-	if !ok {
-		panic("failed")
+	if err := vi.SetAbsorbingState(goal); err != nil {
+		panic(err)
 	}
 	vi.RunValueIteration()
 	vi.UpdatePolicy()
@@ -94,4 +95,189 @@ func TestStateActionVisitation(t *testing.T) {
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestStochasticValueIteration(t *testing.T) {
+	sm, _ := NewStochasticModel(
+		[]int{0, 1, 2},
+		[]StochasticTransition{
+			{FromID: 0, Outcomes: []Outcome{{2, 1}}, Reward: -3},
+			{FromID: 0, Outcomes: []Outcome{{2, 0.5}, {1, 0.5}}, Reward: -1},
+			{FromID: 1, Outcomes: []Outcome{{2, 1}}, Reward: -1},
+		},
+	)
+	svi := NewValueIterator(sm)
+	run(svi, 2)
+	wantV := []float64{-1.5, -1, 0}
+	for i, got := range svi.V {
+		if got != wantV[i] {
+			t.Errorf("V@%d: got %.3f, want %.3f", i, got, wantV[i])
+		}
+	}
+	wantPolicy := []float64{0, 1, 1}
+	for i, got := range svi.Policy {
+		if got != wantPolicy[i] {
+			t.Errorf("Policy@%d: got %.3f, want %.3f", i, got, wantPolicy[i])
+		}
+	}
+
+	stateDist, actionDist := svi.StateActionVisitation([]float64{1, 0, 0})
+	wantStateDist := []float64{1, 0.5, 1}
+	for i, got := range stateDist {
+		if got != wantStateDist[i] {
+			t.Errorf("stateDist@%d: got %.3f, want %.3f", i, got, wantStateDist[i])
+		}
+	}
+	wantActionDist := []float64{0, 1, 0.5}
+	for i, got := range actionDist {
+		if got != wantActionDist[i] {
+			t.Errorf("actionDist@%d: got %.3f, want %.3f", i, got, wantActionDist[i])
+		}
+	}
+
+	tr, err := svi.GenerateTrajectory(0, 2, 10)
+	if err != nil || tr[0] != 0 || tr[len(tr)-1] != 2 {
+		t.Errorf("unexpected trajectory: %v, %v", tr, err)
+	}
+}
+
+
+func TestDiscountedValueIteration(t *testing.T) {
+	dvi := NewValueIterator(m)
+	dvi.SetGamma(0.5)
+	dvi.RunValueIteration()
+	dvi.UpdatePolicy()
+	wantV := []float64{-10.0 / 7, -6.0 / 7, -13.0 / 7, -12.0 / 7}
+	for i, got := range dvi.V {
+		if math.Abs(got - wantV[i]) > 1e-2 {
+			t.Errorf("V@%d: got %.3f, want %.3f", i, got, wantV[i])
+		}
+	}
+	wantPolicy := []float64{1, 0, 1, 1, 1}
+	for i, got := range dvi.Policy {
+		if got != wantPolicy[i] {
+			t.Errorf("Policy@%d: got %.3f, want %.3f", i, got, wantPolicy[i])
+		}
+	}
+
+	stateDist, _ := dvi.StateActionVisitation([]float64{1, 0, 0, 0})
+	wantStateDist := []float64{8.0 / 7, 4.0 / 7, 0, 2.0 / 7}
+	for i, got := range stateDist {
+		if math.Abs(got - wantStateDist[i]) > 1e-3 {
+			t.Errorf("stateDist@%d: got %.3f, want %.3f", i, got, wantStateDist[i])
+		}
+	}
+}
+
+
+func TestConvergenceReport(t *testing.T) {
+	cvi := NewValueIterator(m)
+	cvi.SetAbsorbingState(3)
+	report := cvi.RunValueIteration()
+	if report.HitMaxIterations {
+		t.Errorf("unexpected cap: %v", report)
+	}
+	if len(report.Iterations) != numAnnealing {
+		t.Errorf("got %d stages, want %d", len(report.Iterations), numAnnealing)
+	}
+	if report.Residual >= minTDError {
+		t.Errorf("residual: got %.3f, want < %.3f", report.Residual, minTDError)
+	}
+
+	// without absorbing states, undiscounted Value Iteration never converges
+	opts := DefaultOptions()
+	opts.MaxIterations = 10
+	opts.QueueCapacity = 1
+	cvi = NewValueIteratorWithOptions(m, opts)
+	report = cvi.RunValueIteration()
+	if !report.HitMaxIterations {
+		t.Errorf("expected cap: %v", report)
+	}
+	for i, j := range report.Iterations {
+		if j > opts.MaxIterations {
+			t.Errorf("stage %d: got %d iterations, want <= %d", i, j, opts.MaxIterations)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	if err := DefaultOptions().Validate(); err != nil {
+		t.Errorf("default options: %v", err)
+	}
+	for _, c := range []struct {
+		name string
+		set func(*Options)
+	}{
+		{"MinTDError", func(o *Options) { o.MinTDError = 0 }},
+		{"MinSDError", func(o *Options) { o.MinSDError = -1 }},
+		{"NumAnnealing", func(o *Options) { o.NumAnnealing = 0 }},
+		{"AnnealingRate", func(o *Options) { o.AnnealingRate = 1 }},
+		{"AnnealingRate", func(o *Options) { o.AnnealingRate = 0 }},
+		{"MaxIterations", func(o *Options) { o.MaxIterations = 0 }},
+	} {
+		opts := DefaultOptions()
+		c.set(&opts)
+		err := opts.Validate()
+		if e, ok := err.(*InvalidOptionError); !ok || e.Name != c.name {
+			t.Errorf("%s: got %v, want *InvalidOptionError", c.name, err)
+		}
+		ovi := NewValueIterator(m)
+		if ovi.SetOptions(opts) == nil {
+			t.Errorf("%s: SetOptions accepted invalid options", c.name)
+		}
+		ovi = NewValueIteratorWithOptions(m, opts)
+		if _, err := ovi.RunValueIterationContext(context.Background()); err == nil {
+			t.Errorf("%s: RunValueIterationContext accepted invalid options", c.name)
+		}
+	}
+}
+
+
+func TestRunValueIterationContext(t *testing.T) {
+	cvi := NewValueIterator(m)
+	cvi.SetAbsorbingState(3)
+	stages := 0
+	cvi.SetProgressFunc(func(p Progress) { stages++ })
+	if _, err := cvi.RunValueIterationContext(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if stages != numAnnealing {
+		t.Errorf("got %d progress calls, want %d", stages, numAnnealing)
+	}
+
+	// without absorbing states, only cancellation stops Value Iteration
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cvi.InitAbsorbingState()
+	if _, err := cvi.RunValueIterationContext(ctx); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+	if _, _, err := cvi.StateActionVisitationContext(ctx, []float64{1, 0, 0, 0}); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSetAbsorbingStateAfterSolving(t *testing.T) {
+	// a state set absorbing after a solve must not keep its previous value
+	want := []float64{-2, -1, 0, -3}
+	avi := NewValueIterator(m)
+	avi.SetAbsorbingState(3)
+	avi.RunValueIteration()
+	avi.InitAbsorbingState()
+	avi.SetAbsorbingState(2)
+	avi.RunValueIteration()
+	api := NewPolicyIterator(m)
+	api.SetAbsorbingState(3)
+	api.RunPolicyIteration()
+	api.InitAbsorbingState()
+	api.SetAbsorbingState(2)
+	api.RunPolicyIteration()
+	for i := range want {
+		if math.Abs(avi.V[i] - want[i]) > 1e-6 {
+			t.Errorf("Value Iteration V@%d: got %.3f, want %.3f", i, avi.V[i], want[i])
+		}
+		if math.Abs(api.V[i] - want[i]) > 1e-6 {
+			t.Errorf("Policy Iteration V@%d: got %.3f, want %.3f", i, api.V[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package pomdp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"github.com/misteroda/go-rl/mdp"
+)
+
+// AnyAction is an action label which matches every action in an Observation.
+const AnyAction = -1
+
+const probabilityEps = 1e-6
+
+// ErrImpossibleObservation is returned when an observation has zero probability under a belief.
+var ErrImpossibleObservation = errors.New("pomdp: impossible observation")
+
+// UnknownObservationError reports an observation ID which is not in the Model.
+type UnknownObservationError struct {
+	ID int
+}
+
+func (e *UnknownObservationError) Error() string {
+	return fmt.Sprintf("pomdp: unknown observation ID %d", e.ID)
+}
+
+// DuplicateLabelError reports two actions of a state with the same label.
+type DuplicateLabelError struct {
+	StateID int
+	ActionLabel int
+}
+
+func (e *DuplicateLabelError) Error() string {
+	return fmt.Sprintf("pomdp: duplicate action label %d at state %d", e.ActionLabel, e.StateID)
+}
+
+// InvalidObservationError reports observation probabilities of an action label
+// and a reached state which do not sum to 1.
+type InvalidObservationError struct {
+	ActionLabel int
+	StateID int
+	Sum float64
+}
+
+func (e *InvalidObservationError) Error() string {
+	return fmt.Sprintf("pomdp: observation probabilities of action %d at state %d sum to %g",
+		e.ActionLabel, e.StateID, e.Sum)
+}
+
+// Observation is the probability of observing an observation ID
+// when an action with a label reaches a state.
+type Observation struct {
+	ActionLabel int // label of the action, or AnyAction
+	StateID int // state reached by the action
+	ObservationID int
+	Probability float64
+}
+
+type outcome struct {
+	to int
+	p float64
+}
+
+type observationProbability struct {
+	obs int
+	p float64
+}
+
+// Model is a Partially Observable Markov Decision Process
+// whose dynamics and rewards are given by an mdp.Model.
+// Since the agent does not know its state, actions are identified by labels shared among states,
+// e.g. moving directions. If a state has no action with a label,
+// taking it leaves the agent in the state with reward 0.
+// Beliefs are distributions over states indexed by mdp.State.Index.
+type Model struct {
+	mdp *mdp.Model
+	numLabels int
+	next [][][]outcome // outcomes of each label at each state, staying if nil
+	reward [][]float64 // reward of each label at each state
+	obs [][][]observationProbability // observations of each label at each reached state
+	observationIDs []int
+	observationOf map[int]int
+	isAbsorbing []bool
+}
+
+// NewModel constructs a Model from an mdp.Model and observation probabilities.
+// labels gives the label of each action of m by action index.
+// With nil, each action is labeled by its rank among the actions of its state,
+// i.e. the order of the transitions given to mdp.NewModel.
+// Actions of a state must have distinct labels.
+// Observation probabilities of each label and reached state must sum to 1.
+func NewModel(m *mdp.Model, labels []int, observationIDs []int, observations []Observation) (*Model, error) {
+	sts := m.StochasticTransitions()
+	if labels != nil && len(labels) != len(sts) {
+		return nil, &mdp.DimensionMismatchError{Got: len(labels), Want: len(sts)}
+	}
+	numStates := m.NumStates()
+	if labels == nil {
+		labels = make([]int, len(sts))
+		rank := make(map[int]int)
+		for i, st := range sts {
+			if len(st.Outcomes) == 0 { continue }
+			labels[i] = rank[st.FromID]
+			rank[st.FromID]++
+		}
+	}
+	numLabels := 0
+	for i, st := range sts {
+		if len(st.Outcomes) > 0 && labels[i] + 1 > numLabels {
+			numLabels = labels[i] + 1
+		}
+	}
+	pm := &Model{
+		mdp: m,
+		numLabels: numLabels,
+		next: make([][][]outcome, numStates),
+		reward: make([][]float64, numStates),
+		obs: make([][][]observationProbability, numLabels),
+		observationIDs: observationIDs,
+		observationOf: make(map[int]int),
+		isAbsorbing: make([]bool, numStates),
+	}
+	for i := range pm.next {
+		pm.next[i] = make([][]outcome, numLabels)
+		pm.reward[i] = make([]float64, numLabels)
+	}
+	for i, st := range sts {
+		if len(st.Outcomes) == 0 { continue }
+		if labels[i] < 0 {
+			return nil, &mdp.UnknownActionError{Index: i}
+		}
+		from := m.StateOf[st.FromID].Index()
+		outcomes := make([]outcome, len(st.Outcomes))
+		for j, o := range st.Outcomes {
+			outcomes[j] = outcome{m.StateOf[o.ToID].Index(), o.Probability}
+		}
+		if pm.next[from][labels[i]] != nil {
+			return nil, &DuplicateLabelError{StateID: st.FromID, ActionLabel: labels[i]}
+		}
+		pm.next[from][labels[i]] = outcomes
+		pm.reward[from][labels[i]] = st.Reward
+	}
+
+	for i, id := range observationIDs {
+		pm.observationOf[id] = i
+	}
+	for a := range pm.obs {
+		pm.obs[a] = make([][]observationProbability, numStates)
+	}
+	for _, o := range observations {
+		s, ok := m.StateOf[o.StateID]
+		if !ok { return nil, &mdp.UnknownStateError{ID: o.StateID} }
+		k, ok := pm.observationOf[o.ObservationID]
+		if !ok { return nil, &UnknownObservationError{o.ObservationID} }
+		if o.ActionLabel < AnyAction || o.ActionLabel >= numLabels {
+			return nil, &mdp.UnknownActionError{Index: o.ActionLabel}
+		}
+		for a := range pm.obs {
+			if o.ActionLabel != AnyAction && o.ActionLabel != a { continue }
+			pm.obs[a][s.Index()] = append(pm.obs[a][s.Index()], observationProbability{k, o.Probability})
+		}
+	}
+	ids := m.StateIDs()
+	for a := range pm.obs {
+		for s, ops := range pm.obs[a] {
+			sum := 0.0
+			for _, op := range ops {
+				sum += op.p
+			}
+			if math.Abs(sum - 1) > probabilityEps {
+				return nil, &InvalidObservationError{ActionLabel: a, StateID: ids[s], Sum: sum}
+			}
+		}
+	}
+	return pm, nil
+}
+
+// NumStates returns a number of states.
+func (pm *Model) NumStates() int {
+	return len(pm.next)
+}
+
+// NumActions returns a number of action labels.
+func (pm *Model) NumActions() int {
+	return pm.numLabels
+}
+
+// NumObservations returns a number of observations.
+func (pm *Model) NumObservations() int {
+	return len(pm.observationIDs)
+}
+
+// SetAbsorbingState sets absorbing states, where every action leaves the agent
+// in the state with reward 0.
+func (pm *Model) SetAbsorbingState(stateID int) error {
+	s, ok := pm.mdp.StateOf[stateID]
+	if !ok { return &mdp.UnknownStateError{ID: stateID} }
+	pm.isAbsorbing[s.Index()] = true
+	return nil
+}
+
+// InitAbsorbingState initializes absorbing states.
+func (pm *Model) InitAbsorbingState() {
+	for i := range pm.isAbsorbing {
+		pm.isAbsorbing[i] = false
+	}
+}
+
+// outcomes returns the outcomes of a label at a state.
+func (pm *Model) outcomes(s, a int) []outcome {
+	if pm.isAbsorbing[s] || pm.next[s][a] == nil {
+		return []outcome{{s, 1}}
+	}
+	return pm.next[s][a]
+}
+
+// rewardOf returns the reward of a label at a state.
+func (pm *Model) rewardOf(s, a int) float64 {
+	if pm.isAbsorbing[s] {
+		return 0
+	}
+	return pm.reward[s][a]
+}
+
+// UniformBelief returns the uniform distribution over states.
+func (pm *Model) UniformBelief() []float64 {
+	b := make([]float64, pm.NumStates())
+	for i := range b {
+		b[i] = 1 / float64(len(b))
+	}
+	return b
+}
+
+// UpdateBelief returns the belief after taking an action and observing an observation ID,
+// and the probability of the observation under belief b.
+// It returns ErrImpossibleObservation if the probability is 0.
+func (pm *Model) UpdateBelief(b []float64, action, observationID int) ([]float64, float64, error) {
+	if len(b) != pm.NumStates() {
+		return nil, 0, &mdp.DimensionMismatchError{Got: len(b), Want: pm.NumStates()}
+	}
+	if action < 0 || action >= pm.numLabels {
+		return nil, 0, &mdp.UnknownActionError{Index: action}
+	}
+	k, ok := pm.observationOf[observationID]
+	if !ok { return nil, 0, &UnknownObservationError{observationID} }
+	next := pm.predict(b, action)
+	z := 0.0
+	for s, p := range next {
+		if p == 0 { continue }
+		next[s] = p * pm.observationProbability(action, s, k)
+		z += next[s]
+	}
+	if z <= 0 {
+		return nil, 0, ErrImpossibleObservation
+	}
+	for s := range next {
+		next[s] /= z
+	}
+	return next, z, nil
+}
+
+// predict returns the distribution of states after taking an action under belief b.
+func (pm *Model) predict(b []float64, action int) []float64 {
+	next := make([]float64, len(b))
+	for s, p := range b {
+		if p == 0 { continue }
+		for _, o := range pm.outcomes(s, action) {
+			next[o.to] += p * o.p
+		}
+	}
+	return next
+}
+
+func (pm *Model) observationProbability(action, s, k int) float64 {
+	for _, op := range pm.obs[action][s] {
+		if op.obs == k {
+			return op.p
+		}
+	}
+	return 0
+}
@@ -0,0 +1,279 @@
+package pomdp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"github.com/misteroda/go-rl/mdp"
+)
+
+// ErrDiscount is returned when a discount factor is not in [0, 1).
+var ErrDiscount = errors.New("pomdp: gamma must be in [0, 1)")
+
+// ErrEmptyModel is returned when a Model has no states, action labels or observations to plan with.
+var ErrEmptyModel = errors.New("pomdp: model has no states, action labels or observations")
+
+// AlphaVector is a linear function over beliefs
+// giving the value of taking Action and following the plan below it.
+type AlphaVector struct {
+	Action int
+	Values []float64 // indexed by state index
+}
+
+func (a *AlphaVector) dot(b []float64) float64 {
+	v := 0.0
+	for s, p := range b {
+		v += p * a.Values[s]
+	}
+	return v
+}
+
+// PerseusSolver represents Perseus, a randomized point-based value iteration,
+// which improves the value of every belief in a sampled set at each stage
+// with as few backups as possible.
+type PerseusSolver struct {
+	model *Model
+	AlphaVectors []AlphaVector
+	Beliefs [][]float64
+	gamma float64 // discount factor
+	rand *rand.Rand
+}
+
+// NewPerseusSolver constructs a PerseusSolver instance from a given Model
+// with a discount factor in [0, 1).
+// AlphaVectors start from a single lower bound of values.
+// It returns ErrEmptyModel if the Model has no states, action labels or observations.
+func NewPerseusSolver(model *Model, gamma float64) (*PerseusSolver, error) {
+	if gamma < 0 || gamma >= 1 {
+		return nil, ErrDiscount
+	}
+	if model.NumStates() == 0 || model.NumActions() == 0 || model.NumObservations() == 0 {
+		return nil, ErrEmptyModel
+	}
+	minR := math.Inf(1)
+	for s := range model.reward {
+		for a := range model.reward[s] {
+			minR = math.Min(minR, model.rewardOf(s, a))
+		}
+	}
+	if math.IsInf(minR, 1) {
+		minR = 0
+	}
+	values := make([]float64, model.NumStates())
+	for s := range values {
+		values[s] = minR / (1 - gamma)
+	}
+	return &PerseusSolver{
+		model: model,
+		AlphaVectors: []AlphaVector{{Action: 0, Values: values}},
+		gamma: gamma,
+		rand: rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+// SetRand sets the source of randomness of CollectBeliefs and Run, e.g. seeded for reproducibility.
+func (ps *PerseusSolver) SetRand(r *rand.Rand) {
+	ps.rand = r
+}
+
+// CollectBeliefs sets Beliefs to numBeliefs beliefs reached from an initial belief
+// by random actions in episodes of at most maxDepth steps.
+func (ps *PerseusSolver) CollectBeliefs(initial []float64, numBeliefs, maxDepth int) error {
+	pm := ps.model
+	if len(initial) != pm.NumStates() {
+		return &mdp.DimensionMismatchError{Got: len(initial), Want: pm.NumStates()}
+	}
+	ps.Beliefs = [][]float64{initial}
+	b := initial
+	depth := 0
+	for len(ps.Beliefs) < numBeliefs {
+		if depth >= maxDepth {
+			b, depth = initial, 0
+		}
+		s := sample(ps.rand, b)
+		a := ps.rand.Intn(pm.numLabels)
+		next := sampleOutcome(ps.rand, pm.outcomes(s, a))
+		ops := pm.obs[a][next]
+		k := ops[sampleObservation(ps.rand, ops)].obs
+		nb, _, err := pm.UpdateBelief(b, a, pm.observationIDs[k])
+		if err != nil {
+			return err
+		}
+		ps.Beliefs = append(ps.Beliefs, nb)
+		b = nb
+		depth++
+	}
+	return nil
+}
+
+// Value returns the value of a belief.
+func (ps *PerseusSolver) Value(b []float64) float64 {
+	v, _ := ps.best(b)
+	return v
+}
+
+// BestAction returns the label of the best action at a belief.
+func (ps *PerseusSolver) BestAction(b []float64) int {
+	_, i := ps.best(b)
+	return ps.AlphaVectors[i].Action
+}
+
+func (ps *PerseusSolver) best(b []float64) (float64, int) {
+	bestV, bestI := math.Inf(-1), 0
+	for i := range ps.AlphaVectors {
+		if v := ps.AlphaVectors[i].dot(b); v > bestV {
+			bestV, bestI = v, i
+		}
+	}
+	return bestV, bestI
+}
+
+// Run runs numStages stages of Perseus over Beliefs and updates AlphaVectors.
+// It stops early when no value of Beliefs improves more than minImprovement in a stage,
+// and returns the number of stages run.
+func (ps *PerseusSolver) Run(numStages int, minImprovement float64) int {
+	n, _ := ps.RunContext(context.Background(), numStages, minImprovement)
+	return n
+}
+
+// RunContext runs Run until completion or cancellation of ctx.
+// ctx is checked at each stage.
+func (ps *PerseusSolver) RunContext(ctx context.Context, numStages int, minImprovement float64) (int, error) {
+	var k int
+	for k = 0; k < numStages; k++ {
+		if ctx.Err() != nil {
+			return k, ctx.Err()
+		}
+		if ps.stage() <= minImprovement {
+			k++
+			break
+		}
+	}
+	return k, nil
+}
+
+// stage runs a stage of Perseus and returns the largest improvement of the values of Beliefs.
+func (ps *PerseusSolver) stage() float64 {
+	old := make([]float64, len(ps.Beliefs))
+	for i, b := range ps.Beliefs {
+		old[i] = ps.Value(b)
+	}
+	next := make([]AlphaVector, 0)
+	value := func(b []float64) float64 {
+		v := math.Inf(-1)
+		for i := range next {
+			v = math.Max(v, next[i].dot(b))
+		}
+		return v
+	}
+	remaining := make([]int, len(ps.Beliefs))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	for len(remaining) > 0 {
+		i := remaining[ps.rand.Intn(len(remaining))]
+		b := ps.Beliefs[i]
+		alpha := ps.backup(b)
+		if alpha.dot(b) < old[i] {
+			_, j := ps.best(b)
+			alpha = ps.AlphaVectors[j]
+		}
+		next = append(next, alpha)
+		kept := remaining[:0]
+		for _, j := range remaining {
+			if value(ps.Beliefs[j]) < old[j] {
+				kept = append(kept, j)
+			}
+		}
+		remaining = kept
+	}
+	ps.AlphaVectors = next
+	improvement := 0.0
+	for i, b := range ps.Beliefs {
+		improvement = math.Max(improvement, ps.Value(b) - old[i])
+	}
+	return improvement
+}
+
+// backup returns the alpha vector of the Bellman backup at a belief.
+func (ps *PerseusSolver) backup(b []float64) AlphaVector {
+	pm := ps.model
+	n := pm.NumStates()
+	numObs := pm.NumObservations()
+	var best AlphaVector
+	bestV := math.Inf(-1)
+	for a := 0; a < pm.numLabels; a++ {
+		values := make([]float64, n)
+		for s := range values {
+			values[s] = pm.rewardOf(s, a)
+		}
+		// for each observation, choose the alpha vector which is best for the projected belief
+		for o := 0; o < numObs; o++ {
+			bestG, bestGV := []float64(nil), math.Inf(-1)
+			for i := range ps.AlphaVectors {
+				gi := ps.project(&ps.AlphaVectors[i], a, o)
+				v := 0.0
+				for s, p := range b {
+					v += p * gi[s]
+				}
+				if v > bestGV {
+					bestG, bestGV = gi, v
+				}
+			}
+			for s := range values {
+				values[s] += ps.gamma * bestG[s]
+			}
+		}
+		alpha := AlphaVector{Action: a, Values: values}
+		if v := alpha.dot(b); v > bestV {
+			best, bestV = alpha, v
+		}
+	}
+	return best
+}
+
+// project returns g(s) = sum over s' of T(s' | s, a) O(o | s', a) alpha(s').
+func (ps *PerseusSolver) project(alpha *AlphaVector, a, o int) []float64 {
+	pm := ps.model
+	g := make([]float64, pm.NumStates())
+	for s := range g {
+		for _, oc := range pm.outcomes(s, a) {
+			g[s] += oc.p * pm.observationProbability(a, oc.to, o) * alpha.Values[oc.to]
+		}
+	}
+	return g
+}
+
+func sample(rnd *rand.Rand, p []float64) int {
+	r := rnd.Float64()
+	cumP := 0.0
+	for i, x := range p {
+		cumP += x
+		if r < cumP {
+			return i
+		}
+	}
+	for i := len(p) - 1; i > 0; i-- {
+		if p[i] > 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+func sampleOutcome(rnd *rand.Rand, outcomes []outcome) int {
+	p := make([]float64, len(outcomes))
+	for i, o := range outcomes {
+		p[i] = o.p
+	}
+	return outcomes[sample(rnd, p)].to
+}
+
+func sampleObservation(rnd *rand.Rand, ops []observationProbability) int {
+	p := make([]float64, len(ops))
+	for i, op := range ops {
+		p[i] = op.p
+	}
+	return sample(rnd, p)
+}
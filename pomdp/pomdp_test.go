@@ -0,0 +1,118 @@
+package pomdp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"github.com/misteroda/go-rl/mdp"
+)
+
+const (
+	listen = iota
+	openLeft
+	openRight
+)
+
+const (
+	hearLeft = 10
+	hearRight = 11
+)
+
+// newTiger returns the tiger problem, where the tiger is behind the left door in state 0
+// and behind the right door in state 1.
+func newTiger(t *testing.T) *Model {
+	reset := []mdp.Outcome{{ToID: 0, Probability: 0.5}, {ToID: 1, Probability: 0.5}}
+	m, err := mdp.NewStochasticModel([]int{0, 1}, []mdp.StochasticTransition{
+		{FromID: 0, Outcomes: []mdp.Outcome{{ToID: 0, Probability: 1}}, Reward: -1},
+		{FromID: 0, Outcomes: reset, Reward: -100},
+		{FromID: 0, Outcomes: reset, Reward: 10},
+		{FromID: 1, Outcomes: []mdp.Outcome{{ToID: 1, Probability: 1}}, Reward: -1},
+		{FromID: 1, Outcomes: reset, Reward: 10},
+		{FromID: 1, Outcomes: reset, Reward: -100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := NewModel(m, nil, []int{hearLeft, hearRight}, []Observation{
+		{listen, 0, hearLeft, 0.85},
+		{listen, 0, hearRight, 0.15},
+		{listen, 1, hearLeft, 0.15},
+		{listen, 1, hearRight, 0.85},
+		{openLeft, 0, hearLeft, 0.5},
+		{openLeft, 0, hearRight, 0.5},
+		{openLeft, 1, hearLeft, 0.5},
+		{openLeft, 1, hearRight, 0.5},
+		{openRight, 0, hearLeft, 0.5},
+		{openRight, 0, hearRight, 0.5},
+		{openRight, 1, hearLeft, 0.5},
+		{openRight, 1, hearRight, 0.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pm
+}
+
+func TestBeliefUpdate(t *testing.T) {
+	pm := newTiger(t)
+	b, p, err := pm.UpdateBelief(pm.UniformBelief(), listen, hearLeft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(b[0] - 0.85) > 1e-9 || math.Abs(p - 0.5) > 1e-9 {
+		t.Errorf("belief: got %v with probability %.3f, want [0.85 0.15] with 0.5", b, p)
+	}
+	b, _, _ = pm.UpdateBelief(b, listen, hearLeft)
+	if want := 0.85 * 0.85 / (0.85 * 0.85 + 0.15 * 0.15); math.Abs(b[0] - want) > 1e-9 {
+		t.Errorf("belief: got %.4f, want %.4f", b[0], want)
+	}
+	if _, _, err := pm.UpdateBelief(b, listen, 12); err == nil {
+		t.Errorf("got nil, want *UnknownObservationError")
+	}
+
+	m, _ := mdp.NewModel([]int{0, 1}, []mdp.StateTransition{{FromID: 0, ToID: 1, Reward: -1}})
+	_, err = NewModel(m, nil, []int{0}, []Observation{{AnyAction, 0, 0, 1}, {AnyAction, 1, 0, 0.5}})
+	if _, ok := err.(*InvalidObservationError); !ok {
+		t.Errorf("got %v, want *InvalidObservationError", err)
+	}
+	m, _ = mdp.NewModel([]int{0, 1}, []mdp.StateTransition{{FromID: 0, ToID: 1, Reward: -1}, {FromID: 0, ToID: 0, Reward: -1}})
+	_, err = NewModel(m, []int{0, 0}, []int{0}, []Observation{{AnyAction, 0, 0, 1}, {AnyAction, 1, 0, 1}})
+	if e, ok := err.(*DuplicateLabelError); !ok || e.StateID != 0 || e.ActionLabel != 0 {
+		t.Errorf("got %v, want *DuplicateLabelError", err)
+	}
+}
+
+func TestPerseus(t *testing.T) {
+	pm := newTiger(t)
+	if _, err := NewPerseusSolver(pm, 1); err != ErrDiscount {
+		t.Errorf("got %v, want %v", err, ErrDiscount)
+	}
+	single, _ := mdp.NewModel([]int{0}, nil)
+	empty, err := NewModel(single, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPerseusSolver(empty, 0.95); err != ErrEmptyModel {
+		t.Errorf("got %v, want %v", err, ErrEmptyModel)
+	}
+	ps, _ := NewPerseusSolver(pm, 0.95)
+	ps.SetRand(rand.New(rand.NewSource(1)))
+	if err := ps.CollectBeliefs(pm.UniformBelief(), 200, 10); err != nil {
+		t.Fatal(err)
+	}
+	ps.Run(500, 1e-6)
+
+	uniform := pm.UniformBelief()
+	if a := ps.BestAction(uniform); a != listen {
+		t.Errorf("action at uniform belief: got %d, want listen", a)
+	}
+	b, _, _ := pm.UpdateBelief(uniform, listen, hearLeft)
+	b, _, _ = pm.UpdateBelief(b, listen, hearLeft)
+	if a := ps.BestAction(b); a != openRight {
+		t.Errorf("action after hearing left twice: got %d, want open right", a)
+	}
+	// the optimal value at the uniform belief is about 19.4
+	if v := ps.Value(uniform); math.Abs(v - 19.4) > 1 {
+		t.Errorf("value at uniform belief: got %.3f, want about 19.4", v)
+	}
+}